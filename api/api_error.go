@@ -3,17 +3,154 @@ package api
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrorKind the category of a failed API call, it decides the hint for fixing it
+type ErrorKind string
+
+const (
+	ErrorKindUnknown    ErrorKind = "unknown"
+	ErrorKindAuth       ErrorKind = "auth"
+	ErrorKindPermission ErrorKind = "permission"
+	ErrorKindValidation ErrorKind = "validation"
+	ErrorKindRateLimit  ErrorKind = "rate_limit"
+	ErrorKindNotFound   ErrorKind = "not_found"
 )
 
 // Error an error object used when calling 3rd party APIs failure
 type Error struct {
 	StatusCode   int
+	Header       http.Header
 	ResponseBody []byte
 	Message      string
+	Response     ErrorResponse
+}
+
+// ErrorResponse the body GitHub responds with when a request fails
+type ErrorResponse struct {
+	Message          string        `json:"message"`
+	Errors           []ErrorDetail `json:"errors"`
+	DocumentationURL string        `json:"documentation_url"`
+}
+
+// ErrorDetail tells which field of which resource failed the validation and why
+type ErrorDetail struct {
+	Resource string `json:"resource"`
+	Field    string `json:"field"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+// NewError creates an error of a failed API call, the GitHub error schema of the response body is
+// parsed when the body has one.
+func NewError(statusCode int, header http.Header, responseBody []byte, message string) *Error {
+	apiErr := &Error{
+		StatusCode:   statusCode,
+		Header:       header,
+		ResponseBody: responseBody,
+		Message:      message,
+	}
+	json.Unmarshal(responseBody, &apiErr.Response)
+
+	return apiErr
 }
 
 func (self *Error) Error() string {
-	return self.Message
+	if self.Response.Message == "" {
+		return self.Message
+	}
+
+	message := fmt.Sprintf("%s, reason: %s", self.Message, self.Response.Message)
+
+	var details []string
+	for _, detail := range self.Response.Errors {
+		details = append(details, detail.String())
+	}
+	if len(details) > 0 {
+		message = fmt.Sprintf("%s (%s)", message, strings.Join(details, ", "))
+	}
+
+	return message
+}
+
+func (self ErrorDetail) String() string {
+	var target []string
+	if self.Resource != "" {
+		target = append(target, self.Resource)
+	}
+	if self.Field != "" {
+		target = append(target, self.Field)
+	}
+
+	detail := self.Code
+	if self.Message != "" {
+		detail = self.Message
+	}
+
+	if len(target) == 0 {
+		return detail
+	}
+
+	return fmt.Sprintf("%s: %s", strings.Join(target, "."), detail)
+}
+
+// Kind classifies the error by its status code, GitHub responds 403 when the token lacks a permission
+// and when the rate limit is exceeded.
+func (self *Error) Kind() ErrorKind {
+	switch self.StatusCode {
+	case http.StatusUnauthorized:
+		return ErrorKindAuth
+	case http.StatusForbidden:
+		if self.isRateLimited() {
+			return ErrorKindRateLimit
+		}
+		return ErrorKindPermission
+	case http.StatusTooManyRequests:
+		return ErrorKindRateLimit
+	case http.StatusNotFound:
+		return ErrorKindNotFound
+	case http.StatusUnprocessableEntity:
+		return ErrorKindValidation
+	}
+
+	return ErrorKindUnknown
+}
+
+func (self *Error) isRateLimited() bool {
+	if self.Header != nil && self.Header.Get("X-RateLimit-Remaining") == "0" {
+		return true
+	}
+
+	return strings.Contains(strings.ToLower(self.Response.Message), "rate limit")
+}
+
+// Hint returns what to do to fix the error, it's empty when there's nothing to advise
+func (self *Error) Hint() string {
+	var hint string
+	switch self.Kind() {
+	case ErrorKindAuth:
+		hint = "The token is invalid or expired, check the GITHUB_TOKEN passed to the action"
+	case ErrorKindPermission:
+		hint = "The token lacks checks:write, grant it by `permissions: checks: write` in the workflow. " +
+			"The token of a pull request from a fork is read-only"
+	case ErrorKindRateLimit:
+		hint = "The rate limit of the token is exceeded, re-run the workflow once it's reset"
+	case ErrorKindNotFound:
+		hint = "The repository or the check run isn't found, check GITHUB_REPOSITORY and that the token can access it"
+	case ErrorKindValidation:
+		hint = "GitHub rejected the request, check GITHUB_SHA is a commit of GITHUB_REPOSITORY and the details of the error"
+	default:
+		return ""
+	}
+
+	if self.Response.DocumentationURL != "" {
+		hint = fmt.Sprintf("%s, see %s", hint, self.Response.DocumentationURL)
+	}
+
+	return hint
 }
 
 // ToJSONMap convert ResponseBody to JSON maps
@@ -23,7 +160,9 @@ func (self *Error) ToJSONMap() (map[string]interface{}, error) {
 	}
 
 	var result map[string]interface{}
-	json.Unmarshal(self.ResponseBody, &result)
+	if err := json.Unmarshal(self.ResponseBody, &result); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
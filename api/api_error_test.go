@@ -1,6 +1,7 @@
 package api
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -39,3 +40,81 @@ func Test_calling_the_ToJSONMap_method_when_response_body_is_nil_returns_an_erro
 	assert.Nil(test, result)
 	assert.Equal(test, "ResponseBody must not be nil", err.Error())
 }
+
+func Test_calling_the_ToJSONMap_method_when_response_body_is_no_JSON_returns_an_error(test *testing.T) {
+	apiErr := Error{
+		ResponseBody: []byte("internal server error"),
+	}
+
+	result, err := apiErr.ToJSONMap()
+
+	assert.Nil(test, result)
+	assert.Error(test, err)
+}
+
+func Test_creating_an_error_with_a_GitHub_error_response_parses_the_message_details_and_documentation(test *testing.T) {
+	apiErr := NewError(422, make(http.Header), []byte(`{
+		"message": "Validation Failed",
+		"errors": [
+			{"resource": "CheckRun", "field": "head_sha", "code": "invalid"},
+			{"resource": "CheckRun", "code": "custom", "message": "annotations are too many"}
+		],
+		"documentation_url": "https://developer.github.com/v3/checks/runs/#create-a-check-run"
+	}`), "The API of check run doesn't return 201 HTTP status code")
+
+	assert.Equal(test, "Validation Failed", apiErr.Response.Message)
+	assert.Equal(test, "https://developer.github.com/v3/checks/runs/#create-a-check-run", apiErr.Response.DocumentationURL)
+	assert.Equal(test, []ErrorDetail{
+		{Resource: "CheckRun", Field: "head_sha", Code: "invalid"},
+		{Resource: "CheckRun", Code: "custom", Message: "annotations are too many"},
+	}, apiErr.Response.Errors)
+	assert.Equal(test, "The API of check run doesn't return 201 HTTP status code, reason: Validation Failed "+
+		"(CheckRun.head_sha: invalid, CheckRun: annotations are too many)", apiErr.Error())
+}
+
+func Test_creating_an_error_without_a_GitHub_error_response_returns_the_message(test *testing.T) {
+	apiErr := NewError(502, make(http.Header), []byte("bad gateway"), "error!")
+
+	assert.Equal(test, "error!", apiErr.Error())
+	assert.Equal(test, ErrorKindUnknown, apiErr.Kind())
+	assert.Empty(test, apiErr.Hint())
+}
+
+func Test_calling_the_Kind_method_classifies_the_error_by_its_status_code(test *testing.T) {
+	rateLimited := make(http.Header)
+	rateLimited.Set("X-RateLimit-Remaining", "0")
+
+	cases := []struct {
+		statusCode int
+		header     http.Header
+		body       string
+		expected   ErrorKind
+	}{
+		{401, make(http.Header), `{"message": "Bad credentials"}`, ErrorKindAuth},
+		{403, make(http.Header), `{"message": "Resource not accessible by integration"}`, ErrorKindPermission},
+		{403, rateLimited, `{"message": "API rate limit exceeded"}`, ErrorKindRateLimit},
+		{403, make(http.Header), `{"message": "You have exceeded a secondary rate limit"}`, ErrorKindRateLimit},
+		{429, make(http.Header), `{}`, ErrorKindRateLimit},
+		{404, make(http.Header), `{"message": "Not Found"}`, ErrorKindNotFound},
+		{422, make(http.Header), `{"message": "Validation Failed"}`, ErrorKindValidation},
+		{500, make(http.Header), `{}`, ErrorKindUnknown},
+	}
+
+	for _, c := range cases {
+		apiErr := NewError(c.statusCode, c.header, []byte(c.body), "error!")
+
+		assert.Equal(test, c.expected, apiErr.Kind(), c.body)
+	}
+}
+
+func Test_calling_the_Hint_method_of_a_permission_error_returns_the_missing_permission_and_the_documentation(test *testing.T) {
+	apiErr := NewError(403, make(http.Header), []byte(`{
+		"message": "Resource not accessible by integration",
+		"documentation_url": "https://docs.github.com/rest/reference/checks#create-a-check-run"
+	}`), "error!")
+
+	result := apiErr.Hint()
+
+	assert.Contains(test, result, "checks:write")
+	assert.Contains(test, result, "https://docs.github.com/rest/reference/checks#create-a-check-run")
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"elb2c/gh-action/api"
+	"elb2c/gh-action/config"
+	"elb2c/gh-action/http/httpconst"
+	"elb2c/gh-action/http/httputil"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// tokenRefreshMargin installation tokens are refreshed this long before they expire, so a token
+// doesn't expire in the middle of the annotation.
+const tokenRefreshMargin = 5 * time.Minute
+
+// InstallationTokenAPI provides the installation tokens of a GitHub App. A token is cached until it's
+// about to expire.
+type InstallationTokenAPI struct {
+	client         *http.Client
+	retryPolicy    httputil.RetryPolicy
+	baseURL        string
+	appID          string
+	installationID string
+	repository     string
+	privateKey     *rsa.PrivateKey
+	server         api.Server
+	now            func() time.Time
+
+	mutex     sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+type installation struct {
+	ID int `json:"id"`
+}
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func NewInstallationTokenSource(client *http.Client, URL string, cfg *config.Config, server api.Server) (TokenSource, error) {
+	if cfg == nil {
+		return nil, errors.New("Config must not be nil")
+	}
+
+	content, err := cfg.GitHubAppPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := parsePrivateKey(content)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid environment variable 'GITHUB_APP_PRIVATE_KEY'. %s", err)
+	}
+
+	return &InstallationTokenAPI{
+		client:         client,
+		retryPolicy:    httputil.DefaultRetryPolicy,
+		baseURL:        URL,
+		appID:          cfg.GitHubApp.ID,
+		installationID: cfg.GitHubApp.InstallationID,
+		repository:     cfg.GitHub.Repository,
+		privateKey:     privateKey,
+		server:         server,
+		now:            time.Now,
+	}, nil
+}
+
+func (self *InstallationTokenAPI) Token(ctx context.Context) (string, error) {
+	if self.client == nil {
+		return "", errors.New("HTTP client must not be nil")
+	}
+
+	if self.baseURL == "" {
+		return "", errors.New("Base URL must not be empty")
+	}
+
+	if self.appID == "" {
+		return "", errors.New("GitHub App ID must not be empty")
+	}
+
+	if self.privateKey == nil {
+		return "", errors.New("GitHub App private key must not be nil")
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	now := self.currentTime()
+	if self.token != "" && now.Add(tokenRefreshMargin).Before(self.expiresAt) {
+		return self.token, nil
+	}
+
+	jwt, err := signJWT(self.appID, self.privateKey, now)
+	if err != nil {
+		return "", fmt.Errorf("Failed to sign the JWT of the GitHub App because: %s", err)
+	}
+
+	installationID, err := self.findInstallationID(ctx, jwt)
+	if err != nil {
+		return "", err
+	}
+
+	URL := fmt.Sprintf("%s/app/installations/%s/access_tokens", self.baseURL, installationID)
+	var token installationToken
+	if err := self.submit(ctx, URL, httpconst.MethodPost, jwt, 201, &token); err != nil {
+		return "", err
+	}
+
+	if token.Token == "" {
+		return "", fmt.Errorf("The installation token has no token. URL: %s", URL)
+	}
+
+	log.Printf("Authenticated as the installation %s of the GitHub App %s until %s\n",
+		installationID, self.appID, token.ExpiresAt.Format(time.RFC3339))
+	self.token = token.Token
+	self.expiresAt = token.ExpiresAt
+
+	return self.token, nil
+}
+
+// findInstallationID returns the configured installation or looks up the installation of the repository
+func (self *InstallationTokenAPI) findInstallationID(ctx context.Context, jwt string) (string, error) {
+	if self.installationID != "" {
+		return self.installationID, nil
+	}
+
+	if self.repository == "" {
+		return "", errors.New("GitHub repository must not be empty")
+	}
+
+	URL := fmt.Sprintf("%s/repos/%s/installation", self.baseURL, self.repository)
+	var result installation
+	if err := self.submit(ctx, URL, httpconst.MethodGet, jwt, 200, &result); err != nil {
+		return "", err
+	}
+
+	if result.ID == 0 {
+		return "", fmt.Errorf("The installation of the GitHub App has no ID. URL: %s", URL)
+	}
+
+	// The installation of a repository never changes
+	self.installationID = fmt.Sprint(result.ID)
+
+	return self.installationID, nil
+}
+
+func (self *InstallationTokenAPI) submit(ctx context.Context, URL string, method string, jwt string,
+	successStatusCode int, result interface{}) error {
+
+	header := make(http.Header)
+	header.Add(httpconst.HeaderContentType, httpconst.MediaTypeApplicationJSON)
+	header.Add(httpconst.HeaderAccept, self.server.AppsMediaType())
+	header.Add(httpconst.HeaderAuth, "Bearer "+jwt)
+	header.Add(httpconst.HeaderUserAgent, api.UserAgent)
+
+	resp, err := httputil.SendRequestWithRetry(ctx, self.client, URL, method, header, &bytes.Buffer{}, self.retryPolicy)
+	if err != nil {
+		return fmt.Errorf("Failed to hit the API of GitHub App. method: %s, url: %s, reason: %s", method, URL, err.Error())
+	}
+
+	// The response body holds the token, so it's never logged
+	log.Printf("HTTP response of GitHub App. HTTP status code: %d\n", resp.StatusCode)
+	if resp.StatusCode != successStatusCode {
+		apiErr := api.NewError(resp.StatusCode, resp.Header, resp.Body,
+			fmt.Sprintf("The API of GitHub App doesn't return %d HTTP status code", successStatusCode))
+		if hint := apiErr.Hint(); hint != "" {
+			log.Printf("Hint: %s\n", hint)
+		}
+
+		return apiErr
+	}
+
+	if err := json.Unmarshal(resp.Body, result); err != nil {
+		return fmt.Errorf("Http status code: %d. Failed to unmarshal the response body of GitHub App. method: %s, URL: %s, reason: %s",
+			resp.StatusCode, method, URL, err.Error())
+	}
+
+	return nil
+}
+
+func (self *InstallationTokenAPI) currentTime() time.Time {
+	if self.now == nil {
+		return time.Now()
+	}
+
+	return self.now()
+}
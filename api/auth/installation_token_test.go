@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"elb2c/gh-action/api"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestGitHubApp stands in for the GitHub App APIs, the tokens expire at the given time
+func newTestGitHubApp(test *testing.T, key *rsa.PublicKey, accept string, expiresAt time.Time,
+	requests *[]string) *httptest.Server {
+
+	issued := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		*requests = append(*requests, req.Method+" "+req.URL.Path)
+		assert.Equal(test, accept, req.Header.Get("Accept"))
+		claims := verifyTestJWT(test, strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "), key)
+		assert.Equal(test, "42", claims.Issuer)
+
+		switch {
+		case req.Method == "GET" && req.URL.Path == "/repos/octocat/Hello-World/installation":
+			fmt.Fprint(w, `{"id": 7, "app_id": 42}`)
+		case req.Method == "POST" && req.URL.Path == "/app/installations/7/access_tokens":
+			issued++
+			w.WriteHeader(201)
+			fmt.Fprintf(w, `{"token": "v1.token%d", "expires_at": "%s"}`, issued, expiresAt.Format(time.RFC3339))
+		default:
+			w.WriteHeader(404)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+}
+
+func Test_InstallationTokenAPI_getting_a_token_looks_up_the_installation_and_caches_the_token(test *testing.T) {
+	key := newTestPrivateKey(test)
+	now := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	var requests []string
+	server := newTestGitHubApp(test, &key.PublicKey, "application/vnd.github.v3+json", now.Add(time.Hour), &requests)
+	defer server.Close()
+	api := InstallationTokenAPI{
+		client:     server.Client(),
+		baseURL:    server.URL,
+		appID:      "42",
+		repository: "octocat/Hello-World",
+		privateKey: key,
+		now:        func() time.Time { return now },
+	}
+
+	first, err := api.Token(context.Background())
+	assert.NoError(test, err)
+	second, err := api.Token(context.Background())
+	assert.NoError(test, err)
+
+	assert.Equal(test, "v1.token1", first)
+	assert.Equal(test, "v1.token1", second)
+	assert.Equal(test, []string{
+		"GET /repos/octocat/Hello-World/installation",
+		"POST /app/installations/7/access_tokens",
+	}, requests)
+}
+
+func Test_InstallationTokenAPI_getting_a_token_about_to_expire_refreshes_the_token(test *testing.T) {
+	key := newTestPrivateKey(test)
+	now := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	var requests []string
+	server := newTestGitHubApp(test, &key.PublicKey, "application/vnd.github.machine-man-preview+json",
+		now.Add(time.Hour), &requests)
+	defer server.Close()
+	api := InstallationTokenAPI{
+		client:         server.Client(),
+		baseURL:        server.URL,
+		appID:          "42",
+		installationID: "7",
+		privateKey:     key,
+		server:         api.Server{Enterprise: true, Version: "2.21.0"},
+		now:            func() time.Time { return now },
+	}
+
+	first, err := api.Token(context.Background())
+	assert.NoError(test, err)
+	now = now.Add(56 * time.Minute)
+	second, err := api.Token(context.Background())
+	assert.NoError(test, err)
+
+	assert.Equal(test, "v1.token1", first)
+	assert.Equal(test, "v1.token2", second)
+	assert.Equal(test, []string{
+		"POST /app/installations/7/access_tokens",
+		"POST /app/installations/7/access_tokens",
+	}, requests)
+}
+
+func Test_InstallationTokenAPI_assuming_the_app_not_installed_returns_an_error(test *testing.T) {
+	key := newTestPrivateKey(test)
+	var requests []string
+	server := newTestGitHubApp(test, &key.PublicKey, "application/vnd.github.v3+json", time.Now().Add(time.Hour), &requests)
+	defer server.Close()
+	tokens := InstallationTokenAPI{
+		client:     server.Client(),
+		baseURL:    server.URL,
+		appID:      "42",
+		repository: "octocat/another",
+		privateKey: key,
+	}
+
+	_, err := tokens.Token(context.Background())
+
+	assert.IsType(test, &api.Error{}, err)
+	assert.Equal(test, api.ErrorKindNotFound, err.(*api.Error).Kind())
+}
+
+func Test_InstallationTokenAPI_missing_app_ID_returns_an_error(test *testing.T) {
+	api := InstallationTokenAPI{
+		client:  &http.Client{},
+		baseURL: "http://test.local",
+	}
+
+	_, err := api.Token(context.Background())
+
+	assert.EqualError(test, err, "GitHub App ID must not be empty")
+}
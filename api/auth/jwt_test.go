@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPrivateKey(test *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	return key
+}
+
+// verifyTestJWT verifies the signature of a JWT like GitHub does and returns its claims
+func verifyTestJWT(test *testing.T, jwt string, key *rsa.PublicKey) jwtClaims {
+	parts := strings.Split(jwt, ".")
+	if !assert.Len(test, parts, 3) {
+		return jwtClaims{}
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.NoError(test, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(test, rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature))
+
+	var claims jwtClaims
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.NoError(test, err)
+	assert.NoError(test, json.Unmarshal(payload, &claims))
+
+	return claims
+}
+
+func Test_signing_a_JWT_returns_a_RS256_JWT_issued_by_the_app(test *testing.T) {
+	key := newTestPrivateKey(test)
+	now := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	result, err := signJWT("42", key, now)
+
+	assert.NoError(test, err)
+	header, _ := base64.RawURLEncoding.DecodeString(strings.Split(result, ".")[0])
+	assert.JSONEq(test, `{"alg": "RS256", "typ": "JWT"}`, string(header))
+	claims := verifyTestJWT(test, result, &key.PublicKey)
+	assert.Equal(test, "42", claims.Issuer)
+	assert.Equal(test, now.Add(-time.Minute).Unix(), claims.IssuedAt)
+	assert.Equal(test, now.Add(9*time.Minute).Unix(), claims.ExpiresAt)
+}
+
+func Test_parsing_a_PKCS1_private_key_returns_the_key(test *testing.T) {
+	key := newTestPrivateKey(test)
+	content := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	result, err := parsePrivateKey(content)
+
+	assert.NoError(test, err)
+	assert.Equal(test, key.N, result.N)
+}
+
+func Test_parsing_a_PKCS8_private_key_returns_the_key(test *testing.T) {
+	key := newTestPrivateKey(test)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		test.Fatal(err)
+	}
+	content := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	result, err := parsePrivateKey(content)
+
+	assert.NoError(test, err)
+	assert.Equal(test, key.N, result.N)
+}
+
+func Test_parsing_no_PEM_private_key_returns_an_error(test *testing.T) {
+	_, err := parsePrivateKey([]byte("not a key"))
+
+	assert.EqualError(test, err, "No PEM block found in the private key")
+}
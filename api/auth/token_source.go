@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+	"elb2c/gh-action/api"
+	"elb2c/gh-action/config"
+	"errors"
+	"net/http"
+)
+
+//go:generate mockgen -package=auth -self_package=elb2c/gh-action/api/auth -destination=mock_token_source.go elb2c/gh-action/api/auth TokenSource
+
+// TokenSource provides the token the GitHub API calls are authorized by
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken a token which never changes like GITHUB_TOKEN
+type StaticToken string
+
+func (self StaticToken) Token(ctx context.Context) (string, error) {
+	if self == "" {
+		return "", errors.New("GitHub token must not be empty")
+	}
+
+	return string(self), nil
+}
+
+// NewTokenSource returns the installation tokens of the GitHub App when it's configured, GITHUB_TOKEN
+// otherwise.
+func NewTokenSource(client *http.Client, URL string, cfg *config.Config, server api.Server) (TokenSource, error) {
+	if cfg == nil {
+		return nil, errors.New("Config must not be nil")
+	}
+
+	if !cfg.UseGitHubApp() {
+		return StaticToken(cfg.GitHub.Token), nil
+	}
+
+	return NewInstallationTokenSource(client, URL, cfg, server)
+}
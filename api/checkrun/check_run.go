@@ -0,0 +1,18 @@
+package checkrun
+
+// CheckRun a check run returned by the Checks API
+type CheckRun struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	ExternalID string `json:"external_id"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	HTMLURL    string `json:"html_url"`
+	Output     Output `json:"output"`
+}
+
+// checkRunList the check runs of a commit returned by the Checks API
+type checkRunList struct {
+	TotalCount int        `json:"total_count"`
+	CheckRuns  []CheckRun `json:"check_runs"`
+}
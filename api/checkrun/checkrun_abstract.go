@@ -1,7 +1,10 @@
 package checkrun
 
 import (
+	"context"
 	"elb2c/gh-action/api"
+	"elb2c/gh-action/api/auth"
+	"elb2c/gh-action/config"
 	"elb2c/gh-action/http/httpconst"
 	"elb2c/gh-action/http/httputil"
 	"encoding/json"
@@ -10,65 +13,155 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 const (
 	nameOfCheckRun     = "Test failure annotator"
+	titleOfCheckRun    = "Test failure details"
 	checkRunDateFormat = "2006-01-02T15:04:05Z"
+	// externalIDOfCheckRun tells the check runs of the action apart from others of the same name
+	externalIDOfCheckRun = "go-test-failure-annotator"
 )
 
-func makeHeaders(token string) (header http.Header) {
+// externalIDOf identifies the check run of an attempt of a job of a workflow run, so a re-run of the job
+// supersedes the check runs of its earlier attempts only. The legs of a matrix share the job, they are
+// told apart by the name of the check run.
+func externalIDOf(cfg *config.Config) string {
+	if cfg.GitHub.RunID == "" {
+		return externalIDOfCheckRun
+	}
+
+	attempt := cfg.GitHub.RunAttempt
+	if attempt == "" {
+		attempt = "1"
+	}
+
+	return strings.Join([]string{externalIDOfCheckRun, cfg.GitHub.RunID, cfg.GitHub.Job, attempt}, "/")
+}
+
+// isEarlierAttempt whether the external ID belongs to an earlier attempt of the same job
+func isEarlierAttempt(externalID string, cfg *config.Config) bool {
+	current := strings.Split(externalIDOf(cfg), "/")
+	other := strings.Split(externalID, "/")
+	if len(current) != 4 || len(other) != 4 {
+		return false
+	}
+
+	for i := 0; i < 3; i++ {
+		if current[i] != other[i] {
+			return false
+		}
+	}
+
+	currentAttempt, err := strconv.Atoi(current[3])
+	if err != nil {
+		return false
+	}
+	otherAttempt, err := strconv.Atoi(other[3])
+	if err != nil {
+		return false
+	}
+
+	return otherAttempt < currentAttempt
+}
+
+func makeHeaders(token string, server api.Server) (header http.Header) {
 	header = make(http.Header)
 	header.Add(httpconst.HeaderContentType, httpconst.MediaTypeApplicationJSON)
-	header.Add(httpconst.HeaderAccept, httpconst.MediaTypeGitHubAntiopePreviewJSON)
+	header.Add(httpconst.HeaderAccept, server.ChecksMediaType())
 	header.Add(httpconst.HeaderAuth, "Bearer "+token)
 	header.Add(httpconst.HeaderUserAgent, nameOfCheckRun)
 
 	return header
 }
 
-func submit(client *http.Client, URL string, method string,
-	header http.Header, body io.Reader, successStatusCode int) (map[string]interface{}, error) {
+// authorize returns the token of the token source, GITHUB_TOKEN is used when there's no token source
+func authorize(ctx context.Context, tokens auth.TokenSource, cfg *config.Config) (string, error) {
+	if tokens == nil {
+		tokens = auth.StaticToken(cfg.GitHub.Token)
+	}
+
+	token, err := tokens.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("Failed to authorize the API of check run because: %s", err)
+	}
+
+	return token, nil
+}
+
+// NameOf returns the configured name of the check run or the default one
+func NameOf(cfg *config.Config) string {
+	if name := cfg.CheckRunName(); name != "" {
+		return name
+	}
+
+	return nameOfCheckRun
+}
+
+// TitleOf returns the configured title of the check run output or the default one
+func TitleOf(cfg *config.Config) string {
+	if title := cfg.CheckRunTitle(); title != "" {
+		return title
+	}
+
+	return titleOfCheckRun
+}
+
+// submit sends a request to the Checks API and decodes the response body into result, the body is
+// ignored when result is nil.
+func submit(ctx context.Context, client *http.Client, retryPolicy httputil.RetryPolicy, URL string, method string,
+	header http.Header, body io.Reader, successStatusCode int, result interface{}) error {
+
+	if ctx == nil {
+		return errors.New("Context must not be nil")
+	}
 
 	if client == nil {
-		return nil, errors.New("HTTP client must not be nil")
+		return errors.New("HTTP client must not be nil")
 	}
 
 	if URL == "" {
-		return nil, errors.New("URL must not be empty")
+		return errors.New("URL must not be empty")
 	}
 
 	if method == "" {
-		return nil, errors.New("Method must not be empty")
+		return errors.New("Method must not be empty")
 	}
 
 	if header == nil {
-		return nil, errors.New("Header must not be nil")
+		return errors.New("Header must not be nil")
 	}
 
 	if body == nil {
-		return nil, errors.New("Request body must not be nil")
+		return errors.New("Request body must not be nil")
 	}
 
-	resp, err := httputil.SendRequest(client, URL, method, header, body)
+	resp, err := httputil.SendRequestWithRetry(ctx, client, URL, method, header, body, retryPolicy)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to hit the API of check run. method: %s, url: %s, reason: %s", method, URL, err.Error())
+		return fmt.Errorf("Failed to hit the API of check run. method: %s, url: %s, reason: %s", method, URL, err.Error())
 	}
 
 	log.Printf("HTTP response of check run. HTTP status code: %d, Response body: %s\n", resp.StatusCode, string(resp.Body))
 	if resp.StatusCode != successStatusCode {
-		return nil, &api.Error{
-			StatusCode:   resp.StatusCode,
-			ResponseBody: resp.Body,
-			Message:      fmt.Sprintf("The API of check run doesn't return %d HTTP status code", successStatusCode),
+		apiErr := api.NewError(resp.StatusCode, resp.Header, resp.Body,
+			fmt.Sprintf("The API of check run doesn't return %d HTTP status code", successStatusCode))
+		if hint := apiErr.Hint(); hint != "" {
+			log.Printf("Hint: %s\n", hint)
 		}
+
+		return apiErr
+	}
+
+	if result == nil {
+		return nil
 	}
 
-	var respMap map[string]interface{}
-	if err := json.Unmarshal(resp.Body, &respMap); err != nil {
-		return nil, fmt.Errorf("Http status code: %d. Failed to unmarshal the response body of check run. method: %s, URL: %s, reason: %s",
+	if err := json.Unmarshal(resp.Body, result); err != nil {
+		return fmt.Errorf("Http status code: %d. Failed to unmarshal the response body of check run. method: %s, URL: %s, reason: %s",
 			resp.StatusCode, method, URL, err.Error())
 	}
 
-	return respMap, nil
+	return nil
 }
@@ -2,91 +2,162 @@ package checkrun
 
 import (
 	"bytes"
+	"context"
+	"elb2c/gh-action/api"
+	"elb2c/gh-action/api/auth"
 	"elb2c/gh-action/config"
 	"elb2c/gh-action/http/httpconst"
+	"elb2c/gh-action/http/httputil"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"time"
 )
 
 //go:generate mockgen -package=checkrun -self_package=elb2c/gh-action/api/checkrun -destination=mock_creator.go elb2c/gh-action/api/checkrun Creator
 
 type Creator interface {
-	Create() (int, error)
+	Create(ctx context.Context) (*CheckRun, error)
 }
 
 type CreationAPI struct {
-	client  *http.Client
-	baseURL string
-	config  *config.Config
+	client      *http.Client
+	retryPolicy httputil.RetryPolicy
+	baseURL     string
+	config      *config.Config
+	tokens      auth.TokenSource
+	server      api.Server
 }
 
 type CreationRequestBody struct {
-	Name      string `json:"name"`
-	SHA       string `json:"head_sha"`
-	Status    string `json:"status"`
-	StartedAt string `json:"started_at"`
+	Name       string `json:"name"`
+	SHA        string `json:"head_sha"`
+	ExternalID string `json:"external_id"`
+	Status     string `json:"status"`
+	StartedAt  string `json:"started_at"`
 }
 
-func NewCreator(client *http.Client, URL string, cfg *config.Config) Creator {
+func NewCreator(client *http.Client, URL string, cfg *config.Config, tokens auth.TokenSource,
+	server api.Server) Creator {
+
 	return &CreationAPI{
-		client:  client,
-		baseURL: URL,
-		config:  cfg,
+		client:      client,
+		retryPolicy: httputil.DefaultRetryPolicy,
+		baseURL:     URL,
+		config:      cfg,
+		tokens:      tokens,
+		server:      server,
 	}
 }
 
-func (self *CreationAPI) Create() (int, error) {
+func (self *CreationAPI) Create(ctx context.Context) (*CheckRun, error) {
 	if self.client == nil {
-		return 0, errors.New("HTTP client must not be nil")
+		return nil, errors.New("HTTP client must not be nil")
 	}
 
 	if self.baseURL == "" {
-		return 0, errors.New("Base URL must not be empty")
+		return nil, errors.New("Base URL must not be empty")
 	}
 
 	if self.config == nil {
-		return 0, errors.New("Config must not be nil")
+		return nil, errors.New("Config must not be nil")
 	}
 
 	if self.config.GitHub == (config.GitHub{}) {
-		return 0, errors.New("GitHub config must not be empty")
+		return nil, errors.New("GitHub config must not be empty")
 	}
 
 	if self.config.GitHub.Repository == "" {
-		return 0, errors.New("GitHub repository must not be empty")
+		return nil, errors.New("GitHub repository must not be empty")
 	}
 
-	if self.config.GitHub.Token == "" {
-		return 0, errors.New("GitHub token must not be empty")
+	if self.config.GitHub.Token == "" && self.tokens == nil {
+		return nil, errors.New("GitHub token must not be empty")
 	}
 
 	if self.config.GitHub.SHA == "" {
-		return 0, errors.New("GitHub SHA must not be empty")
+		return nil, errors.New("GitHub SHA must not be empty")
+	}
+
+	token, err := authorize(ctx, self.tokens, self.config)
+	if err != nil {
+		return nil, err
 	}
+	header := makeHeaders(token, self.server)
 
 	URL := fmt.Sprintf("%s/repos/%s/check-runs", self.baseURL, self.config.GitHub.Repository)
 	method := httpconst.MethodPost
-	header := makeHeaders(self.config.GitHub.Token)
 	body := self.makeBody()
 	OKStatusCode := 201
 
-	resp, err := submit(self.client, URL, method, header, body, OKStatusCode)
-	if err != nil {
-		return 0, err
+	var checkRun CheckRun
+	if err := submit(ctx, self.client, self.retryPolicy, URL, method, header, body, OKStatusCode, &checkRun); err != nil {
+		return nil, err
+	}
+
+	if checkRun.ID == 0 {
+		return nil, fmt.Errorf("The created check run has no ID. method: %s, URL: %s", method, URL)
 	}
 
-	return int(resp["id"].(float64)), nil
+	// A re-run gets a new check run, the annotations of a check run can't be removed
+	self.supersede(ctx, header, checkRun.ID)
+
+	return &checkRun, nil
+}
+
+// supersede completes the check runs of the earlier attempts of the job as neutral, so their test
+// failures no longer fail the commit. It's best effort, the new check run is reported either way.
+func (self *CreationAPI) supersede(ctx context.Context, header http.Header, ID int) {
+	if self.config.GitHub.RunID == "" {
+		return
+	}
+
+	URL := fmt.Sprintf("%s/repos/%s/commits/%s/check-runs?check_name=%s&filter=all",
+		self.baseURL, self.config.GitHub.Repository, self.config.GitHub.SHA, url.QueryEscape(NameOf(self.config)))
+	var list checkRunList
+	if err := submit(ctx, self.client, self.retryPolicy, URL, httpconst.MethodGet, header, &bytes.Buffer{}, 200, &list); err != nil {
+		log.Printf("Failed to look up the check runs of the earlier attempts because: %s\n", err)
+		return
+	}
+
+	for _, checkRun := range list.CheckRuns {
+		if checkRun.ID == 0 || checkRun.ID == ID || checkRun.Name != NameOf(self.config) ||
+			!isEarlierAttempt(checkRun.ExternalID, self.config) {
+			continue
+		}
+
+		URL := fmt.Sprintf("%s/repos/%s/check-runs/%d", self.baseURL, self.config.GitHub.Repository, checkRun.ID)
+		req := UpdateRequestBody{
+			Name:        checkRun.Name,
+			SHA:         self.config.GitHub.SHA,
+			Status:      "completed",
+			CompletedAt: time.Now().UTC().Format(checkRunDateFormat),
+			Conclusion:  "neutral",
+			Output: Output{
+				Title:       TitleOf(self.config),
+				Summary:     fmt.Sprintf("Superseded by the check run %d of a re-run", ID),
+				Annotations: make([]Annotation, 0),
+			},
+		}
+		reqJSON, _ := json.Marshal(req)
+		if err := submit(ctx, self.client, self.retryPolicy, URL, httpconst.MethodPatch, header, bytes.NewBuffer(reqJSON), 200, nil); err != nil {
+			log.Printf("Failed to supersede the check run %d because: %s\n", checkRun.ID, err)
+			continue
+		}
+		log.Printf("Superseded the check run %d of an earlier attempt\n", checkRun.ID)
+	}
 }
 
 func (self *CreationAPI) makeBody() *bytes.Buffer {
 	req := CreationRequestBody{
-		Name:      nameOfCheckRun,
-		SHA:       self.config.GitHub.SHA,
-		Status:    "in_progress",
-		StartedAt: time.Now().UTC().Format(checkRunDateFormat),
+		Name:       NameOf(self.config),
+		SHA:        self.config.GitHub.SHA,
+		ExternalID: externalIDOf(self.config),
+		Status:     "in_progress",
+		StartedAt:  time.Now().UTC().Format(checkRunDateFormat),
 	}
 	reqJSON, _ := json.Marshal(req)
 
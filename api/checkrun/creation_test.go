@@ -2,20 +2,26 @@ package checkrun
 
 import (
 	"bytes"
+	"context"
+	"elb2c/gh-action/api/auth"
 	"elb2c/gh-action/config"
+	"elb2c/gh-action/http/httputil"
 	"elb2c/gh-action/testutil"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"testing"
+	"time"
 
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_CreationAPI_missing_HTTP_client_returns_an_error(test *testing.T) {
 	api := CreationAPI{}
 
-	_, err := api.Create()
+	_, err := api.Create(context.Background())
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "HTTP client must not be nil")
@@ -26,7 +32,7 @@ func Test_CreationAPI_missing_base_URL_returns_an_error(test *testing.T) {
 		client: &http.Client{},
 	}
 
-	_, err := api.Create()
+	_, err := api.Create(context.Background())
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "Base URL must not be empty")
@@ -38,7 +44,7 @@ func Test_CreationAPI_missing_config_returns_an_error(test *testing.T) {
 		baseURL: "http://test.local",
 	}
 
-	_, err := api.Create()
+	_, err := api.Create(context.Background())
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "Config must not be nil")
@@ -51,7 +57,7 @@ func Test_CreationAPI_missing_GitHub_config_returns_an_error(test *testing.T) {
 		config:  &config.Config{},
 	}
 
-	_, err := api.Create()
+	_, err := api.Create(context.Background())
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "GitHub config must not be empty")
@@ -70,7 +76,7 @@ func Test_CreationAPI_missing_GitHub_repository_returns_an_error(test *testing.T
 		},
 	}
 
-	_, err := api.Create()
+	_, err := api.Create(context.Background())
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "GitHub repository must not be empty")
@@ -89,7 +95,7 @@ func Test_CreationAPI_missing_GitHub_token_returns_an_error(test *testing.T) {
 		},
 	}
 
-	_, err := api.Create()
+	_, err := api.Create(context.Background())
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "GitHub token must not be empty")
@@ -108,7 +114,7 @@ func Test_CreationAPI_missing_GitHub_SHA_returns_an_error(test *testing.T) {
 		},
 	}
 
-	_, err := api.Create()
+	_, err := api.Create(context.Background())
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "GitHub SHA must not be empty")
@@ -116,11 +122,19 @@ func Test_CreationAPI_missing_GitHub_SHA_returns_an_error(test *testing.T) {
 
 func Test_CreationAPI_passing_valid_HTTP_client_base_URL_and_GitHub_configs_returns_an_ID(test *testing.T) {
 	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		if req.Method == "GET" {
+			return &http.Response{
+				StatusCode: 200,
+				Header:     make(http.Header),
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"total_count": 0, "check_runs": []}`)),
+			}
+		}
+
 		assert.Equal(test, "http://test.local/repos/octocat/Hello-World/check-runs", req.URL.String())
 		assert.Equal(test, "POST", req.Method)
 		// Verify headers
 		assert.Equal(test, "application/json", req.Header.Get("Content-Type"))
-		assert.Equal(test, "application/vnd.github.antiope-preview+json", req.Header.Get("Accept"))
+		assert.Equal(test, "application/vnd.github.v3+json", req.Header.Get("Accept"))
 		assert.Equal(test, "Bearer token", req.Header.Get("Authorization"))
 		assert.Equal(test, "Test failure annotator", req.Header.Get("User-Agent"))
 		// Verify request body
@@ -128,6 +142,7 @@ func Test_CreationAPI_passing_valid_HTTP_client_base_URL_and_GitHub_configs_retu
 		json.Unmarshal(testutil.ToBytes(req.Body), &reqBody)
 		assert.Equal(test, "Test failure annotator", reqBody.Name)
 		assert.Equal(test, "sha", reqBody.SHA)
+		assert.Equal(test, "go-test-failure-annotator", reqBody.ExternalID)
 		assert.Equal(test, "in_progress", reqBody.Status)
 		assert.NotEmpty(test, reqBody.StartedAt)
 
@@ -219,10 +234,45 @@ func Test_CreationAPI_passing_valid_HTTP_client_base_URL_and_GitHub_configs_retu
 		},
 	}
 
-	result, err := api.Create()
+	result, err := api.Create(context.Background())
 
 	assert.NoError(test, err)
-	assert.Equal(test, 4, result)
+	assert.Equal(test, 4, result.ID)
+	assert.Equal(test, "http://github.com/github/hello-world/runs/4", result.HTMLURL)
+}
+
+func Test_CreationAPI_assuming_remote_API_returning_no_ID_returns_an_error(test *testing.T) {
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		if req.Method == "GET" {
+			return &http.Response{
+				StatusCode: 200,
+				Header:     make(http.Header),
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"total_count": 0, "check_runs": []}`)),
+			}
+		}
+
+		return &http.Response{
+			StatusCode: 201,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewBufferString("{}")),
+		}
+	})
+	api := CreationAPI{
+		client:  client,
+		baseURL: "http://test.local",
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				Token:      "token",
+				SHA:        "sha",
+			},
+		},
+	}
+
+	result, err := api.Create(context.Background())
+
+	assert.Nil(test, result)
+	assert.EqualError(test, err, "The created check run has no ID. method: POST, URL: http://test.local/repos/octocat/Hello-World/check-runs")
 }
 
 func Test_CreationAPI_assuming_remote_API_returning_non_201_status_code_returns_an_error(test *testing.T) {
@@ -245,7 +295,408 @@ func Test_CreationAPI_assuming_remote_API_returning_non_201_status_code_returns_
 		},
 	}
 
-	_, err := api.Create()
+	_, err := api.Create(context.Background())
 
 	assert.Error(test, err)
 }
+
+func Test_CreationAPI_assuming_remote_API_returning_an_error_response_returns_an_error_with_its_message(test *testing.T) {
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		return &http.Response{
+			StatusCode: 422,
+			Header:     make(http.Header),
+			Body: ioutil.NopCloser(bytes.NewBufferString(`{
+				"message": "Validation Failed",
+				"documentation_url": "https://developer.github.com/v3/checks/runs/#create-a-check-run"
+			}`)),
+		}
+	})
+	api := CreationAPI{
+		client:  client,
+		baseURL: "http://test.local",
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				Token:      "token",
+				SHA:        "sha",
+			},
+		},
+	}
+
+	_, err := api.Create(context.Background())
+
+	assert.EqualError(test, err, "The API of check run doesn't return 201 HTTP status code, reason: Validation Failed")
+}
+
+func Test_CreationAPI_assuming_remote_API_returning_502_status_code_returns_an_error_without_creating_another_check_run(test *testing.T) {
+	attempts := 0
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		if req.Method == "GET" {
+			return &http.Response{
+				StatusCode: 200,
+				Header:     make(http.Header),
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"total_count": 0, "check_runs": []}`)),
+			}
+		}
+
+		attempts++
+		if attempts == 1 {
+			return &http.Response{
+				StatusCode: 502,
+				Header:     make(http.Header),
+				Body:       ioutil.NopCloser(bytes.NewBufferString("bad gateway")),
+			}
+		}
+
+		return &http.Response{
+			StatusCode: 201,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id": 4}`)),
+		}
+	})
+	api := CreationAPI{
+		client: client,
+		retryPolicy: httputil.RetryPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    time.Millisecond,
+		},
+		baseURL: "http://test.local",
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				Token:      "token",
+				SHA:        "sha",
+			},
+		},
+	}
+
+	result, err := api.Create(context.Background())
+
+	// The check run may have been created, another one would stay in progress forever
+	assert.Error(test, err)
+	assert.Nil(test, result)
+	assert.Equal(test, 1, attempts)
+}
+
+func Test_CreationAPI_assuming_remote_API_rate_limiting_once_retries_and_returns_an_ID(test *testing.T) {
+	attempts := 0
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		if req.Method == "GET" {
+			return &http.Response{
+				StatusCode: 200,
+				Header:     make(http.Header),
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"total_count": 0, "check_runs": []}`)),
+			}
+		}
+
+		attempts++
+		if attempts == 1 {
+			return &http.Response{
+				StatusCode: 429,
+				Header:     make(http.Header),
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"message": "API rate limit exceeded"}`)),
+			}
+		}
+
+		return &http.Response{
+			StatusCode: 201,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id": 4}`)),
+		}
+	})
+	api := CreationAPI{
+		client: client,
+		retryPolicy: httputil.RetryPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    time.Millisecond,
+		},
+		baseURL: "http://test.local",
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				Token:      "token",
+				SHA:        "sha",
+			},
+		},
+	}
+
+	result, err := api.Create(context.Background())
+
+	assert.NoError(test, err)
+	assert.Equal(test, 4, result.ID)
+	assert.Equal(test, 2, attempts)
+}
+
+func Test_CreationAPI_assuming_a_check_run_of_an_earlier_attempt_existing_creates_a_new_ID_and_neutralizes_the_earlier_one(test *testing.T) {
+	var requests []string
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		requests = append(requests, req.Method+" "+req.URL.String())
+		switch req.Method {
+		case "GET":
+			return &http.Response{
+				StatusCode: 200,
+				Header:     make(http.Header),
+				Body: ioutil.NopCloser(bytes.NewBufferString(`{
+					"total_count": 5,
+					"check_runs": [
+						{"id": 9, "name": "Test failure annotator", "external_id": "go-test-failure-annotator/42/test/2", "status": "in_progress"},
+						{"id": 5, "name": "Test failure annotator", "external_id": "another-app", "status": "completed"},
+						{"id": 6, "name": "Test failure annotator", "external_id": "go-test-failure-annotator/41/test/1", "status": "completed"},
+						{"id": 7, "name": "Test failure annotator", "external_id": "go-test-failure-annotator/42/lint/1", "status": "completed"},
+						{"id": 8, "name": "Test failure annotator", "external_id": "go-test-failure-annotator/42/test/1", "status": "completed"}
+					]
+				}`)),
+			}
+		case "PATCH":
+			// Verify request body
+			var reqBody UpdateRequestBody
+			json.Unmarshal(testutil.ToBytes(req.Body), &reqBody)
+			assert.Equal(test, "completed", reqBody.Status)
+			assert.Equal(test, "neutral", reqBody.Conclusion)
+			assert.Equal(test, "Superseded by the check run 9 of a re-run", reqBody.Output.Summary)
+			assert.Empty(test, reqBody.Output.Annotations)
+
+			return &http.Response{
+				StatusCode: 200,
+				Header:     make(http.Header),
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id": 8}`)),
+			}
+		}
+
+		// Verify request body
+		var reqBody CreationRequestBody
+		json.Unmarshal(testutil.ToBytes(req.Body), &reqBody)
+		assert.Equal(test, "go-test-failure-annotator/42/test/2", reqBody.ExternalID)
+
+		return &http.Response{
+			StatusCode: 201,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id": 9}`)),
+		}
+	})
+	api := CreationAPI{
+		client:  client,
+		baseURL: "http://test.local",
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				Token:      "token",
+				SHA:        "sha",
+				RunID:      "42",
+				RunAttempt: "2",
+				Job:        "test",
+			},
+		},
+	}
+
+	result, err := api.Create(context.Background())
+
+	assert.NoError(test, err)
+	assert.Equal(test, 9, result.ID)
+	assert.Equal(test, []string{
+		"POST http://test.local/repos/octocat/Hello-World/check-runs",
+		"GET http://test.local/repos/octocat/Hello-World/commits/sha/check-runs?check_name=Test+failure+annotator&filter=all",
+		"PATCH http://test.local/repos/octocat/Hello-World/check-runs/8",
+	}, requests)
+}
+
+func Test_CreationAPI_assuming_the_earlier_check_run_failing_to_be_neutralized_returns_the_new_ID(test *testing.T) {
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		switch req.Method {
+		case "GET":
+			return &http.Response{
+				StatusCode: 200,
+				Header:     make(http.Header),
+				Body: ioutil.NopCloser(bytes.NewBufferString(`{
+					"total_count": 1,
+					"check_runs": [{"id": 8, "name": "Test failure annotator", "external_id": "go-test-failure-annotator/42/test/1"}]
+				}`)),
+			}
+		case "PATCH":
+			return &http.Response{
+				StatusCode: 422,
+				Header:     make(http.Header),
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"message": "Validation Failed"}`)),
+			}
+		}
+
+		return &http.Response{
+			StatusCode: 201,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id": 9}`)),
+		}
+	})
+	api := CreationAPI{
+		client:  client,
+		baseURL: "http://test.local",
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				Token:      "token",
+				SHA:        "sha",
+				RunID:      "42",
+				RunAttempt: "2",
+				Job:        "test",
+			},
+		},
+	}
+
+	result, err := api.Create(context.Background())
+
+	assert.NoError(test, err)
+	assert.Equal(test, 9, result.ID)
+}
+
+func Test_CreationAPI_running_in_parallel_with_the_same_attempt_neutralizes_no_check_run(test *testing.T) {
+	var requests []string
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		requests = append(requests, req.Method+" "+req.URL.String())
+		if req.Method == "GET" {
+			return &http.Response{
+				StatusCode: 200,
+				Header:     make(http.Header),
+				Body: ioutil.NopCloser(bytes.NewBufferString(`{
+					"total_count": 2,
+					"check_runs": [
+						{"id": 8, "name": "Test failure annotator", "external_id": "go-test-failure-annotator/42/test/1", "status": "in_progress"},
+						{"id": 9, "name": "Test failure annotator", "external_id": "go-test-failure-annotator/42/test/1", "status": "in_progress"}
+					]
+				}`)),
+			}
+		}
+
+		return &http.Response{
+			StatusCode: 201,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id": 9}`)),
+		}
+	})
+	api := CreationAPI{
+		client:  client,
+		baseURL: "http://test.local",
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				Token:      "token",
+				SHA:        "sha",
+				RunID:      "42",
+				RunAttempt: "1",
+				Job:        "test",
+			},
+		},
+	}
+
+	result, err := api.Create(context.Background())
+
+	assert.NoError(test, err)
+	assert.Equal(test, 9, result.ID)
+	assert.Equal(test, []string{
+		"POST http://test.local/repos/octocat/Hello-World/check-runs",
+		"GET http://test.local/repos/octocat/Hello-World/commits/sha/check-runs?check_name=Test+failure+annotator&filter=all",
+	}, requests)
+}
+
+func Test_CreationAPI_setting_a_check_run_name_creates_the_check_run_by_that_name(test *testing.T) {
+	var requests []string
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		requests = append(requests, req.Method+" "+req.URL.String())
+
+		// Verify request body
+		var reqBody CreationRequestBody
+		json.Unmarshal(testutil.ToBytes(req.Body), &reqBody)
+		assert.Equal(test, "Test failures (go 1.13)", reqBody.Name)
+
+		return &http.Response{
+			StatusCode: 201,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id": 9}`)),
+		}
+	})
+	api := CreationAPI{
+		client:  client,
+		baseURL: "http://test.local",
+		config: &config.Config{
+			CheckRun: config.CheckRun{
+				Name: "Test failures (go 1.13)",
+			},
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				Token:      "token",
+				SHA:        "sha",
+			},
+		},
+	}
+
+	result, err := api.Create(context.Background())
+
+	assert.NoError(test, err)
+	assert.Equal(test, 9, result.ID)
+	assert.Equal(test, []string{
+		"POST http://test.local/repos/octocat/Hello-World/check-runs",
+	}, requests)
+}
+
+func Test_CreationAPI_passing_a_token_source_authorizes_the_requests_by_its_token(test *testing.T) {
+	mockCtl := gomock.NewController(test)
+	defer mockCtl.Finish()
+
+	tokensMock := auth.NewMockTokenSource(mockCtl)
+	tokensMock.EXPECT().Token(gomock.Any()).Return("v1.installation", nil)
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		assert.Equal(test, "Bearer v1.installation", req.Header.Get("Authorization"))
+		if req.Method == "GET" {
+			return &http.Response{
+				StatusCode: 200,
+				Header:     make(http.Header),
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"total_count": 0, "check_runs": []}`)),
+			}
+		}
+
+		return &http.Response{
+			StatusCode: 201,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id": 4}`)),
+		}
+	})
+	api := CreationAPI{
+		client:  client,
+		baseURL: "http://test.local",
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				SHA:        "sha",
+			},
+		},
+		tokens: tokensMock,
+	}
+
+	result, err := api.Create(context.Background())
+
+	assert.NoError(test, err)
+	assert.Equal(test, 4, result.ID)
+}
+
+func Test_CreationAPI_assuming_the_token_source_failing_returns_an_error(test *testing.T) {
+	mockCtl := gomock.NewController(test)
+	defer mockCtl.Finish()
+
+	tokensMock := auth.NewMockTokenSource(mockCtl)
+	tokensMock.EXPECT().Token(gomock.Any()).Return("", errors.New("Bad credentials"))
+	api := CreationAPI{
+		client:  &http.Client{},
+		baseURL: "http://test.local",
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				SHA:        "sha",
+			},
+		},
+		tokens: tokensMock,
+	}
+
+	_, err := api.Create(context.Background())
+
+	assert.EqualError(test, err, "Failed to authorize the API of check run because: Bad credentials")
+}
@@ -0,0 +1,36 @@
+package checkrun
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxOutputLength the Checks API rejects a summary, text, annotation message or raw details longer
+// than 64KB
+const maxOutputLength = 65535
+
+// truncate cuts the text at the last line which fits the limit, a note tells how much is left out.
+// A text without a line break fitting the limit is cut at the last whole character.
+func truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+
+	// Reserve room for the note, its length grows with the number of the omitted bytes
+	note := fmt.Sprintf("\n\n... truncated %d bytes", len(text))
+	cut := limit - len(note)
+	if cut <= 0 {
+		return ""
+	}
+
+	// A line break too far back would leave out more than the limit demands
+	if index := strings.LastIndex(text[:cut], "\n"); index > cut/2 {
+		cut = index
+	}
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+
+	return text[:cut] + fmt.Sprintf("\n\n... truncated %d bytes", len(text)-cut)
+}
@@ -0,0 +1,40 @@
+package checkrun
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_truncating_a_text_within_the_limit_returns_the_text(test *testing.T) {
+	result := truncate("Error: Not equal", 16)
+
+	assert.Equal(test, "Error: Not equal", result)
+}
+
+func Test_truncating_a_text_over_the_limit_cuts_it_at_a_line_break_and_notes_the_omitted_bytes(test *testing.T) {
+	text := strings.Repeat("expected: 1\n", 10)
+
+	result := truncate(text, 100)
+
+	assert.True(test, len(result) <= 100)
+	assert.Equal(test, strings.Repeat("expected: 1\n", 5)+"expected: 1\n\n... truncated 49 bytes", result)
+}
+
+func Test_truncating_a_text_of_multibyte_characters_never_splits_a_character(test *testing.T) {
+	text := strings.Repeat("測", 100)
+
+	result := truncate(text, 50)
+
+	assert.True(test, len(result) <= 50)
+	assert.True(test, strings.HasPrefix(result, "測測測測測測測測"))
+	assert.NotContains(test, result, "�")
+	assert.Contains(test, result, "... truncated")
+}
+
+func Test_truncating_a_text_to_a_limit_shorter_than_the_note_returns_an_empty_text(test *testing.T) {
+	result := truncate("Error: Not equal", 5)
+
+	assert.Equal(test, "", result)
+}
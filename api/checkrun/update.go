@@ -2,8 +2,12 @@ package checkrun
 
 import (
 	"bytes"
+	"context"
+	"elb2c/gh-action/api"
+	"elb2c/gh-action/api/auth"
 	"elb2c/gh-action/config"
 	"elb2c/gh-action/http/httpconst"
+	"elb2c/gh-action/http/httputil"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,54 +17,81 @@ import (
 
 //go:generate mockgen -package=checkrun -self_package=elb2c/gh-action/api/checkrun -destination=mock_updater.go elb2c/gh-action/api/checkrun Updater
 
+// maxAnnotationsPerRequest the Checks API accepts at most 50 annotations per request
+const maxAnnotationsPerRequest = 50
+
 type Updater interface {
-	Update(checkID int, annotations []Annotation) error
+	Update(ctx context.Context, checkID int, result Result) error
 }
 
 type UpdateAPI struct {
-	client  *http.Client
-	baseURL string
-	config  *config.Config
+	client      *http.Client
+	retryPolicy httputil.RetryPolicy
+	baseURL     string
+	config      *config.Config
+	tokens      auth.TokenSource
+	server      api.Server
 }
 
 type UpdateRequestBody struct {
 	Name        string `json:"name"`
 	SHA         string `json:"head_sha"`
-	Status      string `json:"status"`
-	CompletedAt string `json:"completed_at"`
-	Conclusion  string `json:"conclusion"`
+	Status      string `json:"status,omitempty"`
+	CompletedAt string `json:"completed_at,omitempty"`
+	Conclusion  string `json:"conclusion,omitempty"`
+	DetailsURL  string `json:"details_url,omitempty"`
 	Output      Output `json:"output"`
 }
 
 type Output struct {
 	Title       string       `json:"title"`
 	Summary     string       `json:"summary"`
+	Text        string       `json:"text,omitempty"`
 	Annotations []Annotation `json:"annotations"`
 }
 
+// Result the outcome of the tests which completes a check run, the conclusion is determined
+// by the annotations when it's empty.
+type Result struct {
+	Conclusion  string
+	DetailsURL  string
+	Summary     string
+	Text        string
+	Annotations []Annotation
+}
+
+// Annotation the columns are only allowed when the annotation starts and ends on the same line
 type Annotation struct {
-	Title     string `json:"title"`
-	Path      string `json:"path"`
-	StartLine int    `json:"start_line"`
-	EndLine   int    `json:"end_line"`
-	Level     string `json:"annotation_level"`
-	Message   string `json:"message"`
+	Title       string `json:"title"`
+	Path        string `json:"path"`
+	StartLine   int    `json:"start_line"`
+	EndLine     int    `json:"end_line"`
+	StartColumn int    `json:"start_column,omitempty"`
+	EndColumn   int    `json:"end_column,omitempty"`
+	Level       string `json:"annotation_level"`
+	Message     string `json:"message"`
+	RawDetails  string `json:"raw_details,omitempty"`
 }
 
-func NewUpdater(client *http.Client, URL string, cfg *config.Config) Updater {
+func NewUpdater(client *http.Client, URL string, cfg *config.Config, tokens auth.TokenSource,
+	server api.Server) Updater {
+
 	return &UpdateAPI{
-		client:  client,
-		baseURL: URL,
-		config:  cfg,
+		client:      client,
+		retryPolicy: httputil.DefaultRetryPolicy,
+		baseURL:     URL,
+		config:      cfg,
+		tokens:      tokens,
+		server:      server,
 	}
 }
 
-func (self *UpdateAPI) Update(checkID int, annotations []Annotation) error {
+func (self *UpdateAPI) Update(ctx context.Context, checkID int, result Result) error {
 	if checkID == 0 {
 		return errors.New("Invalid check ID")
 	}
 
-	if annotations == nil {
+	if result.Annotations == nil {
 		return errors.New("Annotation array must not be nil")
 	}
 
@@ -84,7 +115,7 @@ func (self *UpdateAPI) Update(checkID int, annotations []Annotation) error {
 		return errors.New("GitHub repository must not be empty")
 	}
 
-	if self.config.GitHub.Token == "" {
+	if self.config.GitHub.Token == "" && self.tokens == nil {
 		return errors.New("GitHub token must not be empty")
 	}
 
@@ -94,35 +125,68 @@ func (self *UpdateAPI) Update(checkID int, annotations []Annotation) error {
 
 	URL := fmt.Sprintf("%s/repos/%s/check-runs/%d", self.baseURL, self.config.GitHub.Repository, checkID)
 	method := httpconst.MethodPatch
-	header := makeHeaders(self.config.GitHub.Token)
-	body := self.makeBody(annotations)
+	token, err := authorize(ctx, self.tokens, self.config)
+	if err != nil {
+		return err
+	}
+	header := makeHeaders(token, self.server)
 	OKStatusCode := 200
 
-	_, err := submit(self.client, URL, method, header, body, OKStatusCode)
+	// Upload the annotations in batches, the last request completes the check run
+	annotations := result.Annotations
+	for len(annotations) > maxAnnotationsPerRequest {
+		body := self.makeBody(result, annotations[:maxAnnotationsPerRequest], false)
+		if err := submit(ctx, self.client, self.retryPolicy, URL, method, header, body, OKStatusCode, &CheckRun{}); err != nil {
+			return err
+		}
+		annotations = annotations[maxAnnotationsPerRequest:]
+	}
 
-	return err
+	body := self.makeBody(result, annotations, true)
+	return submit(ctx, self.client, self.retryPolicy, URL, method, header, body, OKStatusCode, &CheckRun{})
 }
 
-func (self *UpdateAPI) makeBody(annotations []Annotation) *bytes.Buffer {
+func (self *UpdateAPI) makeBody(result Result, annotations []Annotation, completed bool) *bytes.Buffer {
+	summary := result.Summary
+	if summary == "" {
+		summary = fmt.Sprintf("%d test failure(s) found", len(result.Annotations))
+	}
+
+	// Fields over the limits of the Checks API fail the whole request
+	limited := make([]Annotation, len(annotations))
+	for i, annotation := range annotations {
+		annotation.Message = truncate(annotation.Message, maxOutputLength)
+		annotation.RawDetails = truncate(annotation.RawDetails, maxOutputLength)
+		limited[i] = annotation
+	}
+
 	req := UpdateRequestBody{
-		Name:        nameOfCheckRun,
-		SHA:         self.config.GitHub.SHA,
-		Status:      "completed",
-		CompletedAt: time.Now().UTC().Format(checkRunDateFormat),
-		Conclusion:  self.determineConclusion(annotations),
+		Name: NameOf(self.config),
+		SHA:  self.config.GitHub.SHA,
 		Output: Output{
-			Title:       "Test failure details",
-			Summary:     fmt.Sprintf("%d test failure(s) found", len(annotations)),
-			Annotations: annotations,
+			Title:       TitleOf(self.config),
+			Summary:     truncate(summary, maxOutputLength),
+			Text:        truncate(result.Text, maxOutputLength),
+			Annotations: limited,
 		},
 	}
+	if completed {
+		req.Status = "completed"
+		req.CompletedAt = time.Now().UTC().Format(checkRunDateFormat)
+		req.Conclusion = self.determineConclusion(result)
+		req.DetailsURL = result.DetailsURL
+	}
 	reqJSON, _ := json.Marshal(req)
 
 	return bytes.NewBuffer(reqJSON)
 }
 
-func (self *UpdateAPI) determineConclusion(annotations []Annotation) string {
-	if len(annotations) > 0 {
+func (self *UpdateAPI) determineConclusion(result Result) string {
+	if result.Conclusion != "" {
+		return result.Conclusion
+	}
+
+	if len(result.Annotations) > 0 {
 		return "failure"
 	}
 
@@ -2,11 +2,15 @@ package checkrun
 
 import (
 	"bytes"
+	"context"
+	"elb2c/gh-action/api"
 	"elb2c/gh-action/config"
 	"elb2c/gh-action/testutil"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,7 +20,7 @@ func Test_UpdateAPI_passing_invalid_check_ID_returns_an_error(test *testing.T) {
 	api := UpdateAPI{}
 	annotations := make([]Annotation, 0)
 
-	err := api.Update(0, annotations)
+	err := api.Update(context.Background(), 0, Result{Annotations: annotations})
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "Invalid check ID")
@@ -25,7 +29,7 @@ func Test_UpdateAPI_passing_invalid_check_ID_returns_an_error(test *testing.T) {
 func Test_UpdateAPI_passing_nil_annotation_array_returns_an_error(test *testing.T) {
 	api := UpdateAPI{}
 
-	err := api.Update(1, nil)
+	err := api.Update(context.Background(), 1, Result{})
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "Annotation array must not be nil")
@@ -35,7 +39,7 @@ func Test_UpdateAPI_missing_HTTP_client_returns_an_error(test *testing.T) {
 	api := UpdateAPI{}
 	annotations := make([]Annotation, 0)
 
-	err := api.Update(1, annotations)
+	err := api.Update(context.Background(), 1, Result{Annotations: annotations})
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "HTTP client must not be nil")
@@ -47,7 +51,7 @@ func Test_UpdateAPI_missing_base_URL_returns_an_error(test *testing.T) {
 	}
 	annotations := make([]Annotation, 0)
 
-	err := api.Update(1, annotations)
+	err := api.Update(context.Background(), 1, Result{Annotations: annotations})
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "Base URL must not be empty")
@@ -60,7 +64,7 @@ func Test_UpdateAPI_missing_config_returns_an_error(test *testing.T) {
 	}
 	annotations := make([]Annotation, 0)
 
-	err := api.Update(1, annotations)
+	err := api.Update(context.Background(), 1, Result{Annotations: annotations})
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "Config must not be nil")
@@ -74,7 +78,7 @@ func Test_UpdateAPI_missing_GitHub_config_returns_an_error(test *testing.T) {
 	}
 	annotations := make([]Annotation, 0)
 
-	err := api.Update(1, annotations)
+	err := api.Update(context.Background(), 1, Result{Annotations: annotations})
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "GitHub config must not be empty")
@@ -94,7 +98,7 @@ func Test_UpdateAPI_missing_GitHub_repository_returns_an_error(test *testing.T)
 	}
 	annotations := make([]Annotation, 0)
 
-	err := api.Update(1, annotations)
+	err := api.Update(context.Background(), 1, Result{Annotations: annotations})
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "GitHub repository must not be empty")
@@ -114,7 +118,7 @@ func Test_UpdateAPI_missing_GitHub_token_returns_an_error(test *testing.T) {
 	}
 	annotations := make([]Annotation, 0)
 
-	err := api.Update(1, annotations)
+	err := api.Update(context.Background(), 1, Result{Annotations: annotations})
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "GitHub token must not be empty")
@@ -134,7 +138,7 @@ func Test_UpdateAPI_missing_GitHub_SHA_returns_an_error(test *testing.T) {
 	}
 	annotations := make([]Annotation, 0)
 
-	err := api.Update(1, annotations)
+	err := api.Update(context.Background(), 1, Result{Annotations: annotations})
 
 	assert.Error(test, err)
 	assert.Equal(test, err.Error(), "GitHub SHA must not be empty")
@@ -146,7 +150,7 @@ func Test_UpdateAPI_passing_valid_HTTP_client_base_URL_and_GitHub_configs_return
 		assert.Equal(test, "PATCH", req.Method)
 		// Verify headers
 		assert.Equal(test, "application/json", req.Header.Get("Content-Type"))
-		assert.Equal(test, "application/vnd.github.antiope-preview+json", req.Header.Get("Accept"))
+		assert.Equal(test, "application/vnd.github.v3+json", req.Header.Get("Accept"))
 		assert.Equal(test, "Bearer token", req.Header.Get("Authorization"))
 		assert.Equal(test, "Test failure annotator", req.Header.Get("User-Agent"))
 		// Verify request body
@@ -194,7 +198,7 @@ func Test_UpdateAPI_passing_valid_HTTP_client_base_URL_and_GitHub_configs_return
 	}
 	annotations = append(annotations, annotation)
 
-	err := api.Update(1, annotations)
+	err := api.Update(context.Background(), 1, Result{Annotations: annotations})
 
 	assert.NoError(test, err)
 }
@@ -220,7 +224,7 @@ func Test_UpdateAPI_assuming_remote_API_returning_non_200_status_code_returns_an
 	}
 	annotations := make([]Annotation, 0)
 
-	err := api.Update(1, annotations)
+	err := api.Update(context.Background(), 1, Result{Annotations: annotations})
 
 	assert.Error(test, err)
 }
@@ -252,7 +256,277 @@ func Test_UpdateAPI_passing_zero_annotation_returns_no_error(test *testing.T) {
 	}
 	annotations := make([]Annotation, 0)
 
-	err := api.Update(1, annotations)
+	err := api.Update(context.Background(), 1, Result{Annotations: annotations})
+
+	assert.NoError(test, err)
+}
+
+func Test_UpdateAPI_passing_a_summary_returns_no_error_and_sends_the_summary(test *testing.T) {
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		// Verify request body
+		var reqBody UpdateRequestBody
+		json.Unmarshal(testutil.ToBytes(req.Body), &reqBody)
+		assert.Equal(test, "0 test failure(s) found\n\n- `test_report.xml`: 0 test failure(s)", reqBody.Output.Summary)
+
+		return &http.Response{
+			StatusCode: 200,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewBufferString("{}")),
+		}
+	})
+	api := UpdateAPI{
+		client:  client,
+		baseURL: "http://test.local",
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				Token:      "token",
+				SHA:        "sha",
+			},
+		},
+	}
+	result := Result{
+		Summary:     "0 test failure(s) found\n\n- `test_report.xml`: 0 test failure(s)",
+		Annotations: make([]Annotation, 0),
+	}
+
+	err := api.Update(context.Background(), 1, result)
+
+	assert.NoError(test, err)
+}
+
+func Test_UpdateAPI_passing_a_conclusion_returns_no_error_and_sends_the_conclusion(test *testing.T) {
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		// Verify request body
+		var reqBody UpdateRequestBody
+		json.Unmarshal(testutil.ToBytes(req.Body), &reqBody)
+		assert.Equal(test, "failure", reqBody.Conclusion)
+		assert.Equal(test, 0, len(reqBody.Output.Annotations))
+
+		return &http.Response{
+			StatusCode: 200,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewBufferString("{}")),
+		}
+	})
+	api := UpdateAPI{
+		client:  client,
+		baseURL: "http://test.local",
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				Token:      "token",
+				SHA:        "sha",
+			},
+		},
+	}
+	result := Result{
+		Conclusion:  "failure",
+		Annotations: make([]Annotation, 0),
+	}
+
+	err := api.Update(context.Background(), 1, result)
+
+	assert.NoError(test, err)
+}
+
+func Test_UpdateAPI_passing_more_than_50_annotations_returns_no_error_and_uploads_them_in_batches(test *testing.T) {
+	var batches []UpdateRequestBody
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		assert.Equal(test, "http://test.local/repos/octocat/Hello-World/check-runs/1", req.URL.String())
+		assert.Equal(test, "PATCH", req.Method)
+
+		var reqBody UpdateRequestBody
+		json.Unmarshal(testutil.ToBytes(req.Body), &reqBody)
+		batches = append(batches, reqBody)
+
+		return &http.Response{
+			StatusCode: 200,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewBufferString("{}")),
+		}
+	})
+	api := UpdateAPI{
+		client:  client,
+		baseURL: "http://test.local",
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				Token:      "token",
+				SHA:        "sha",
+			},
+		},
+	}
+	annotations := make([]Annotation, 0)
+	for i := 1; i <= 120; i++ {
+		annotations = append(annotations, Annotation{
+			Title:     fmt.Sprintf("Test_%d", i),
+			Path:      "api/api_handler.go",
+			StartLine: i,
+			EndLine:   i,
+			Level:     "failure",
+			Message:   "Because you passed errors",
+		})
+	}
+
+	err := api.Update(context.Background(), 1, Result{Annotations: annotations})
+
+	assert.NoError(test, err)
+	assert.Equal(test, 3, len(batches))
+
+	assert.Equal(test, 50, len(batches[0].Output.Annotations))
+	assert.Equal(test, "Test_1", batches[0].Output.Annotations[0].Title)
+	assert.Empty(test, batches[0].Status)
+	assert.Empty(test, batches[0].Conclusion)
+	assert.Equal(test, "120 test failure(s) found", batches[0].Output.Summary)
+
+	assert.Equal(test, 50, len(batches[1].Output.Annotations))
+	assert.Equal(test, "Test_51", batches[1].Output.Annotations[0].Title)
+	assert.Empty(test, batches[1].Status)
+	assert.Empty(test, batches[1].Conclusion)
+
+	assert.Equal(test, 20, len(batches[2].Output.Annotations))
+	assert.Equal(test, "Test_101", batches[2].Output.Annotations[0].Title)
+	assert.Equal(test, "completed", batches[2].Status)
+	assert.NotEmpty(test, batches[2].CompletedAt)
+	assert.Equal(test, "failure", batches[2].Conclusion)
+}
+
+func Test_UpdateAPI_assuming_a_batch_of_annotations_failing_to_upload_returns_an_error(test *testing.T) {
+	requests := 0
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		requests++
+
+		return &http.Response{
+			StatusCode: 422,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"message": "Validation Failed"}`)),
+		}
+	})
+	api := UpdateAPI{
+		client:  client,
+		baseURL: "http://test.local",
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				Token:      "token",
+				SHA:        "sha",
+			},
+		},
+	}
+	annotations := make([]Annotation, 60)
+
+	err := api.Update(context.Background(), 1, Result{Annotations: annotations})
+
+	assert.Error(test, err)
+	assert.Equal(test, 1, requests)
+}
+
+func Test_UpdateAPI_setting_a_check_run_name_and_title_returns_no_error_and_sends_them(test *testing.T) {
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		// Verify request body
+		var reqBody UpdateRequestBody
+		json.Unmarshal(testutil.ToBytes(req.Body), &reqBody)
+		assert.Equal(test, "Test failures (go 1.13)", reqBody.Name)
+		assert.Equal(test, "Test failures on Linux", reqBody.Output.Title)
+
+		return &http.Response{
+			StatusCode: 200,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewBufferString("{}")),
+		}
+	})
+	api := UpdateAPI{
+		client:  client,
+		baseURL: "http://test.local",
+		config: &config.Config{
+			CheckRun: config.CheckRun{
+				Name:  "Test failures (go 1.13)",
+				Title: "Test failures on Linux",
+			},
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				Token:      "token",
+				SHA:        "sha",
+			},
+		},
+	}
+
+	err := api.Update(context.Background(), 1, Result{Annotations: make([]Annotation, 0)})
+
+	assert.NoError(test, err)
+}
+
+func Test_UpdateAPI_passing_a_GitHub_Enterprise_Server_older_than_3_sends_the_preview_media_type(test *testing.T) {
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		assert.Equal(test, "application/vnd.github.antiope-preview+json", req.Header.Get("Accept"))
+
+		return &http.Response{
+			StatusCode: 200,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewBufferString("{}")),
+		}
+	})
+	api := UpdateAPI{
+		client:  client,
+		baseURL: "https://github.example.com/api/v3",
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				Token:      "token",
+				SHA:        "sha",
+			},
+		},
+		server: api.Server{Enterprise: true, Version: "2.21.3"},
+	}
+
+	err := api.Update(context.Background(), 1, Result{Annotations: make([]Annotation, 0)})
+
+	assert.NoError(test, err)
+}
+
+func Test_UpdateAPI_passing_a_text_and_raw_details_over_the_limits_sends_them_truncated(test *testing.T) {
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		// Verify request body
+		var reqBody UpdateRequestBody
+		json.Unmarshal(testutil.ToBytes(req.Body), &reqBody)
+		assert.True(test, len(reqBody.Output.Text) <= 65535)
+		assert.Contains(test, reqBody.Output.Text, "... truncated")
+		assert.Equal(test, "Error: Not equal", reqBody.Output.Annotations[0].Message)
+		assert.True(test, len(reqBody.Output.Annotations[0].RawDetails) <= 65535)
+		assert.True(test, strings.HasPrefix(reqBody.Output.Annotations[0].RawDetails, "Error Trace:"))
+
+		return &http.Response{
+			StatusCode: 200,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewBufferString("{}")),
+		}
+	})
+	api := UpdateAPI{
+		client:  client,
+		baseURL: "http://test.local",
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				Token:      "token",
+				SHA:        "sha",
+			},
+		},
+	}
+	result := Result{
+		Text: strings.Repeat("=== RUN   TestList\n", 5000),
+		Annotations: []Annotation{{
+			Title:      "TestList",
+			Path:       "handler/user_handler_test.go",
+			StartLine:  53,
+			EndLine:    53,
+			Level:      "failure",
+			Message:    "Error: Not equal",
+			RawDetails: "Error Trace:\tuser_handler_test.go:53\n" + strings.Repeat("-  (string) (len=2) \"id\": (float64) 11,\n", 2000),
+		}},
+	}
+
+	err := api.Update(context.Background(), 1, result)
 
 	assert.NoError(test, err)
 }
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"elb2c/gh-action/http/httpconst"
+	"elb2c/gh-action/http/httputil"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// UserAgent the User-Agent of the requests to the GitHub API
+const UserAgent = "Test failure annotator"
+
+// headerEnterpriseVersion GitHub Enterprise Server responds its version in every response
+const headerEnterpriseVersion = "X-GitHub-Enterprise-Version"
+
+// Server the GitHub server the API calls go to, GitHub Enterprise Server lags behind github.com in the
+// features of the APIs. The zero value is github.com.
+type Server struct {
+	Enterprise bool
+	Version    string
+}
+
+type meta struct {
+	InstalledVersion string `json:"installed_version"`
+}
+
+// DetectServer tells github.com and GitHub Enterprise Server apart by the meta API. The server is
+// assumed to be github.com when the detection fails.
+func DetectServer(ctx context.Context, client *http.Client, URL string) Server {
+	header := make(http.Header)
+	header.Add(httpconst.HeaderAccept, httpconst.MediaTypeGitHubV3JSON)
+	header.Add(httpconst.HeaderUserAgent, UserAgent)
+
+	resp, err := httputil.SendRequest(ctx, client, URL+"/meta", httpconst.MethodGet, header, &bytes.Buffer{})
+	if err != nil {
+		log.Printf("Failed to detect the GitHub server, assume github.com because: %s\n", err)
+		return Server{}
+	}
+
+	// The meta API of a server in private mode needs authentication, the header is responded anyway
+	if version := resp.Header.Get(headerEnterpriseVersion); version != "" {
+		return Server{Enterprise: true, Version: version}
+	}
+
+	var result meta
+	if json.Unmarshal(resp.Body, &result) == nil && result.InstalledVersion != "" {
+		return Server{Enterprise: true, Version: result.InstalledVersion}
+	}
+
+	return Server{}
+}
+
+// ChecksMediaType returns the media type of the Checks API, it's a preview before GitHub Enterprise
+// Server 3.0.
+func (self Server) ChecksMediaType() string {
+	if self.previewRequired() {
+		return httpconst.MediaTypeGitHubAntiopePreviewJSON
+	}
+
+	return httpconst.MediaTypeGitHubV3JSON
+}
+
+// AppsMediaType returns the media type of the GitHub App APIs, it's a preview before GitHub Enterprise
+// Server 3.0.
+func (self Server) AppsMediaType() string {
+	if self.previewRequired() {
+		return httpconst.MediaTypeGitHubMachineManPreviewJSON
+	}
+
+	return httpconst.MediaTypeGitHubV3JSON
+}
+
+func (self Server) String() string {
+	if !self.Enterprise {
+		return "github.com"
+	}
+
+	return "GitHub Enterprise Server " + self.Version
+}
+
+// previewRequired whether the server is a GitHub Enterprise Server older than 3.0, a server of an
+// unknown version is assumed to be old.
+func (self Server) previewRequired() bool {
+	if !self.Enterprise {
+		return false
+	}
+
+	major, err := strconv.Atoi(strings.SplitN(self.Version, ".", 2)[0])
+	if err != nil {
+		return true
+	}
+
+	return major < 3
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_detecting_the_server_responding_the_enterprise_version_header_returns_GitHub_Enterprise_Server(test *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(test, "/api/v3/meta", req.URL.Path)
+		w.Header().Set("X-GitHub-Enterprise-Version", "2.21.3")
+		w.WriteHeader(401)
+		fmt.Fprint(w, `{"message": "Must authenticate to access this API."}`)
+	}))
+	defer server.Close()
+
+	result := DetectServer(context.Background(), server.Client(), server.URL+"/api/v3")
+
+	assert.Equal(test, Server{Enterprise: true, Version: "2.21.3"}, result)
+	assert.Equal(test, "application/vnd.github.antiope-preview+json", result.ChecksMediaType())
+	assert.Equal(test, "application/vnd.github.machine-man-preview+json", result.AppsMediaType())
+}
+
+func Test_detecting_the_server_responding_the_installed_version_returns_GitHub_Enterprise_Server(test *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `{"verifiable_password_authentication": true, "installed_version": "3.1.0"}`)
+	}))
+	defer server.Close()
+
+	result := DetectServer(context.Background(), server.Client(), server.URL)
+
+	assert.Equal(test, Server{Enterprise: true, Version: "3.1.0"}, result)
+	assert.Equal(test, "application/vnd.github.v3+json", result.ChecksMediaType())
+	assert.Equal(test, "application/vnd.github.v3+json", result.AppsMediaType())
+}
+
+func Test_detecting_the_server_responding_no_version_returns_github_com(test *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `{"verifiable_password_authentication": true, "hooks": ["192.30.252.0/22"]}`)
+	}))
+	defer server.Close()
+
+	result := DetectServer(context.Background(), server.Client(), server.URL)
+
+	assert.Equal(test, Server{}, result)
+	assert.Equal(test, "github.com", result.String())
+	assert.Equal(test, "application/vnd.github.v3+json", result.ChecksMediaType())
+}
+
+func Test_detecting_an_unreachable_server_returns_github_com(test *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	result := DetectServer(context.Background(), server.Client(), server.URL)
+
+	assert.Equal(test, Server{}, result)
+}
+
+func Test_a_GitHub_Enterprise_Server_of_an_unknown_version_requires_the_preview_media_types(test *testing.T) {
+	server := Server{Enterprise: true, Version: "unknown"}
+
+	assert.Equal(test, "application/vnd.github.antiope-preview+json", server.ChecksMediaType())
+	assert.Equal(test, "GitHub Enterprise Server unknown", server.String())
+}
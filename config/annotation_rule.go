@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// AnnotationRule decides the annotation level of the test failures it matches, a failure matches when
+// it matches all the selectors of the rule.
+type AnnotationRule struct {
+	Level string
+	// Package matches the import path of the package or the directory of the file, `...` matches any
+	// string like the package patterns of `go test`
+	Package *regexp.Regexp
+	// Test matches the name of the test
+	Test *regexp.Regexp
+	// Category matches how the test failed, e.g. panic
+	Category string
+}
+
+const annotationRulesEnv = "ANNOTATION_LEVEL_RULES"
+
+var (
+	annotationLevels  = []string{"failure", "warning", "notice"}
+	failureCategories = []string{"assertion", "error", "panic", "timeout"}
+)
+
+// AnnotationRules parses the rules of the annotation levels. A rule is a level followed by its selectors
+// like `warning package=./e2e/... test=^TestIntegration`, the rules are separated by new lines or `;`.
+func (self *Config) AnnotationRules() ([]AnnotationRule, error) {
+	var rules []AnnotationRule
+	for _, line := range strings.FieldsFunc(self.AnnotationLevelRules, func(r rune) bool { return r == '\n' || r == ';' }) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		rule, err := parseAnnotationRule(fields)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid environment variable '%s'. %s in the rule '%s'", annotationRulesEnv,
+				err, strings.TrimSpace(line))
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func parseAnnotationRule(fields []string) (AnnotationRule, error) {
+	rule := AnnotationRule{Level: fields[0]}
+	if !contains(annotationLevels, rule.Level) {
+		return rule, fmt.Errorf("the level should be one of '%s' instead of '%s'",
+			strings.Join(annotationLevels, "', '"), rule.Level)
+	}
+
+	if len(fields) == 1 {
+		return rule, errors.New("no selector")
+	}
+
+	for _, field := range fields[1:] {
+		pair := strings.SplitN(field, "=", 2)
+		if len(pair) != 2 || pair[1] == "" {
+			return rule, fmt.Errorf("the selector '%s' should be key=pattern", field)
+		}
+
+		var err error
+		switch pair[0] {
+		case "package":
+			rule.Package, err = compilePackagePattern(pair[1])
+		case "test":
+			rule.Test, err = regexp.Compile(pair[1])
+		case "category":
+			if !contains(failureCategories, pair[1]) {
+				err = fmt.Errorf("the category should be one of '%s' instead of '%s'",
+					strings.Join(failureCategories, "', '"), pair[1])
+			}
+			rule.Category = pair[1]
+		default:
+			err = fmt.Errorf("the selector should be one of 'package', 'test', 'category' instead of '%s'", pair[0])
+		}
+		if err != nil {
+			return rule, err
+		}
+	}
+
+	return rule, nil
+}
+
+// compilePackagePattern converts a package pattern to a regexp, `./` is dropped since the directories
+// are relative to the repository and a trailing `/...` matches the directory itself too.
+func compilePackagePattern(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimPrefix(pattern, "./")
+
+	suffix := ""
+	if strings.HasSuffix(pattern, "/...") {
+		pattern = strings.TrimSuffix(pattern, "/...")
+		suffix = "(/.*)?"
+	}
+
+	expression := regexp.QuoteMeta(pattern)
+	expression = strings.Replace(expression, `\.\.\.`, ".*", -1)
+	expression = strings.Replace(expression, `\*`, "[^/]*", -1)
+
+	return regexp.Compile("^" + expression + suffix + "$")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_setting_no_annotation_level_rules_returns_no_rules(test *testing.T) {
+	cfg := Config{}
+
+	result, err := cfg.AnnotationRules()
+
+	assert.NoError(test, err)
+	assert.Empty(test, result)
+}
+
+func Test_setting_annotation_level_rules_returns_the_rules_in_order(test *testing.T) {
+	cfg := Config{
+		AnnotationLevelRules: "warning package=./e2e/... test=^TestIntegration\n" +
+			"notice category=timeout; failure package=example.com/app/*/unit",
+	}
+
+	result, err := cfg.AnnotationRules()
+
+	assert.NoError(test, err)
+	assert.Equal(test, 3, len(result))
+
+	assert.Equal(test, "warning", result[0].Level)
+	assert.True(test, result[0].Package.MatchString("e2e"))
+	assert.True(test, result[0].Package.MatchString("e2e/checkout"))
+	assert.False(test, result[0].Package.MatchString("e2etest"))
+	assert.True(test, result[0].Test.MatchString("TestIntegrationCheckout"))
+	assert.Empty(test, result[0].Category)
+
+	assert.Equal(test, "notice", result[1].Level)
+	assert.Nil(test, result[1].Package)
+	assert.Nil(test, result[1].Test)
+	assert.Equal(test, "timeout", result[1].Category)
+
+	assert.Equal(test, "failure", result[2].Level)
+	assert.True(test, result[2].Package.MatchString("example.com/app/cart/unit"))
+	assert.False(test, result[2].Package.MatchString("example.com/app/cart/api/unit"))
+}
+
+func Test_setting_an_unknown_annotation_level_returns_an_error(test *testing.T) {
+	cfg := Config{AnnotationLevelRules: "error package=./e2e/..."}
+
+	_, err := cfg.AnnotationRules()
+
+	assert.EqualError(test, err, "Invalid environment variable 'ANNOTATION_LEVEL_RULES'. "+
+		"the level should be one of 'failure', 'warning', 'notice' instead of 'error' in the rule 'error package=./e2e/...'")
+}
+
+func Test_setting_an_annotation_level_rule_without_selectors_returns_an_error(test *testing.T) {
+	cfg := Config{AnnotationLevelRules: "warning"}
+
+	_, err := cfg.AnnotationRules()
+
+	assert.EqualError(test, err, "Invalid environment variable 'ANNOTATION_LEVEL_RULES'. no selector in the rule 'warning'")
+}
+
+func Test_setting_an_unknown_selector_of_an_annotation_level_rule_returns_an_error(test *testing.T) {
+	cfg := Config{AnnotationLevelRules: "warning file=e2e_test.go"}
+
+	_, err := cfg.AnnotationRules()
+
+	assert.EqualError(test, err, "Invalid environment variable 'ANNOTATION_LEVEL_RULES'. "+
+		"the selector should be one of 'package', 'test', 'category' instead of 'file' in the rule 'warning file=e2e_test.go'")
+}
+
+func Test_setting_an_invalid_test_regexp_of_an_annotation_level_rule_returns_an_error(test *testing.T) {
+	cfg := Config{AnnotationLevelRules: "warning test=Test("}
+
+	_, err := cfg.AnnotationRules()
+
+	assert.Error(test, err)
+}
+
+func Test_setting_an_unknown_category_of_an_annotation_level_rule_returns_an_error(test *testing.T) {
+	cfg := Config{AnnotationLevelRules: "notice category=flaky"}
+
+	_, err := cfg.AnnotationRules()
+
+	assert.EqualError(test, err, "Invalid environment variable 'ANNOTATION_LEVEL_RULES'. "+
+		"the category should be one of 'assertion', 'error', 'panic', 'timeout' instead of 'flaky' in the rule 'notice category=flaky'")
+}
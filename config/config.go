@@ -1,23 +1,84 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/joeshaw/envdecode"
 )
 
+// How the test failures are reported
+const (
+	ReporterCheckRun        = "check-run"
+	ReporterWorkflowCommand = "workflow-command"
+)
+
 type Config struct {
-	TestResultFile string `env:"TEST_RESULT,required"`
+	TestResultFile       string        `env:"TEST_RESULT,required"`
+	TestResultFormat     string        `env:"TEST_RESULT_FORMAT"`
+	AnnotatePanicOrigin  bool          `env:"ANNOTATE_PANIC_ORIGIN,default=true"`
+	AnnotationLevelRules string        `env:"ANNOTATION_LEVEL_RULES"`
+	Reporter             string        `env:"REPORTER,default=check-run"`
+	Timeout              time.Duration `env:"TIMEOUT,default=5m"`
+	RequestTimeout       time.Duration `env:"REQUEST_TIMEOUT,default=30s"`
+	CheckRun             CheckRun
+	GitHubApp            GitHubApp
+	Connection           Connection
+	Conclusion           Conclusion
 	GitHub
 }
 
+// Conclusion how the test failures conclude the check run. The tests fail when the failures exceed
+// the allowed count and, when it's set, the allowed percentage of the tests.
+type Conclusion struct {
+	OnFailure                string  `env:"CONCLUSION_ON_FAILURE,default=failure"`
+	OnMissingReport          string  `env:"CONCLUSION_ON_MISSING_REPORT,default=failure"`
+	AllowedFailures          int     `env:"ALLOWED_FAILURES,default=0"`
+	AllowedFailurePercentage float64 `env:"ALLOWED_FAILURE_PERCENTAGE,default=0"`
+}
+
+// Connection how the GitHub API is reached, GitHub Enterprise Server is often behind a corporate proxy
+// and signed by an internal CA. HTTPS_PROXY and NO_PROXY are used when GITHUB_PROXY is empty.
+type Connection struct {
+	CABundle   string `env:"GITHUB_CA_BUNDLE"`
+	Proxy      string `env:"GITHUB_PROXY"`
+	ClientCert string `env:"GITHUB_CLIENT_CERT"`
+	ClientKey  string `env:"GITHUB_CLIENT_KEY"`
+}
+
+// CheckRun the appearance of the check run, environment variables like ${GO_VERSION} in the values are
+// expanded so every leg of a matrix build reports under its own name.
+type CheckRun struct {
+	Name  string `env:"CHECK_RUN_NAME"`
+	Title string `env:"CHECK_RUN_TITLE"`
+}
+
+// GitHubApp the GitHub App the action authenticates as instead of using GITHUB_TOKEN. The private key
+// is either the PEM content or the path of a PEM file, the installation of the repository is looked up
+// when its ID is empty.
+type GitHubApp struct {
+	ID             string `env:"GITHUB_APP_ID"`
+	PrivateKey     string `env:"GITHUB_APP_PRIVATE_KEY"`
+	InstallationID string `env:"GITHUB_APP_INSTALLATION_ID"`
+}
+
 type GitHub struct {
-	URL        string `env:"GITHUB_API_URL,required"`
-	SHA        string `env:"GITHUB_SHA,required"`
-	Token      string `env:"GITHUB_TOKEN,required"`
-	Workspace  string `env:"GITHUB_WORKSPACE,required"`
-	Repository string `env:"GITHUB_REPOSITORY,required"`
+	URL         string `env:"GITHUB_API_URL,required"`
+	SHA         string `env:"GITHUB_SHA,required"`
+	Token       string `env:"GITHUB_TOKEN"`
+	Workspace   string `env:"GITHUB_WORKSPACE,required"`
+	Repository  string `env:"GITHUB_REPOSITORY,required"`
+	ServerURL   string `env:"GITHUB_SERVER_URL,default=https://github.com"`
+	RunID       string `env:"GITHUB_RUN_ID"`
+	RunAttempt  string `env:"GITHUB_RUN_ATTEMPT"`
+	Job         string `env:"GITHUB_JOB"`
+	StepSummary string `env:"GITHUB_STEP_SUMMARY"`
 }
 
 func Load() (Config, error) {
@@ -26,9 +87,110 @@ func Load() (Config, error) {
 		return cfg, err
 	}
 
+	if cfg.Reporter != ReporterCheckRun && cfg.Reporter != ReporterWorkflowCommand {
+		return cfg, fmt.Errorf("Invalid environment variable 'REPORTER'. should be one of '%s', '%s' instead of '%s'",
+			ReporterCheckRun, ReporterWorkflowCommand, cfg.Reporter)
+	}
+
+	// GITHUB_TOKEN is only optional when the action authenticates as a GitHub App or calls no API
+	if cfg.Token == "" && !cfg.UseGitHubApp() && !cfg.UseWorkflowCommands() {
+		return cfg, errors.New(`the environment variable "GITHUB_TOKEN" is missing`)
+	}
+
+	if cfg.UseGitHubApp() && cfg.GitHubApp.PrivateKey == "" {
+		return cfg, errors.New(`the environment variable "GITHUB_APP_PRIVATE_KEY" is missing`)
+	}
+
+	if err := cfg.verifyConclusion(); err != nil {
+		return cfg, err
+	}
+
+	if _, err := cfg.AnnotationRules(); err != nil {
+		return cfg, err
+	}
+
 	return cfg, nil
 }
 
+// APIURL returns the URL of the GitHub API. The REST API of GitHub Enterprise Server is served
+// under /api/v3, so it's appended when GITHUB_API_URL is the bare URL of the server.
+func (self *Config) APIURL() string {
+	URL := strings.TrimSuffix(self.GitHub.URL, "/")
+	parsed, err := url.Parse(URL)
+	if err != nil || parsed.Host == "" || parsed.Host == "api.github.com" {
+		return URL
+	}
+
+	if parsed.Path == "" {
+		return URL + "/api/v3"
+	}
+
+	return URL
+}
+
+// RunURL returns the URL of the workflow run, it's empty outside of GitHub Actions
+func (self *Config) RunURL() string {
+	if self.GitHub.RunID == "" || self.GitHub.ServerURL == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s/%s/actions/runs/%s", strings.TrimSuffix(self.GitHub.ServerURL, "/"),
+		self.GitHub.Repository, self.GitHub.RunID)
+}
+
+func (self *Config) verifyConclusion() error {
+	if self.Conclusion.OnFailure != "failure" && self.Conclusion.OnFailure != "neutral" {
+		return fmt.Errorf("Invalid environment variable 'CONCLUSION_ON_FAILURE'. should be one of 'failure', 'neutral' instead of '%s'",
+			self.Conclusion.OnFailure)
+	}
+
+	switch self.Conclusion.OnMissingReport {
+	case "failure", "neutral", "action_required", "success":
+	default:
+		return fmt.Errorf("Invalid environment variable 'CONCLUSION_ON_MISSING_REPORT'. should be one of 'failure', 'neutral', 'action_required', 'success' instead of '%s'",
+			self.Conclusion.OnMissingReport)
+	}
+
+	if self.Conclusion.AllowedFailures < 0 {
+		return fmt.Errorf("Invalid environment variable 'ALLOWED_FAILURES'. should not be negative instead of '%d'",
+			self.Conclusion.AllowedFailures)
+	}
+
+	if self.Conclusion.AllowedFailurePercentage < 0 || self.Conclusion.AllowedFailurePercentage > 100 {
+		return fmt.Errorf("Invalid environment variable 'ALLOWED_FAILURE_PERCENTAGE'. should be between 0 and 100 instead of '%g'",
+			self.Conclusion.AllowedFailurePercentage)
+	}
+
+	return nil
+}
+
+// UseWorkflowCommands whether the test failures are reported by the workflow commands printed to the
+// standard output instead of a check run
+func (self *Config) UseWorkflowCommands() bool {
+	return self.Reporter == ReporterWorkflowCommand
+}
+
+// UseGitHubApp whether the action authenticates as a GitHub App
+func (self *Config) UseGitHubApp() bool {
+	return self.GitHubApp.ID != ""
+}
+
+// GitHubAppPrivateKey returns the PEM private key of the GitHub App, GITHUB_APP_PRIVATE_KEY may
+// be the path of the PEM file.
+func (self *Config) GitHubAppPrivateKey() ([]byte, error) {
+	key := strings.TrimSpace(self.GitHubApp.PrivateKey)
+	if strings.HasPrefix(key, "-----BEGIN") {
+		return []byte(key), nil
+	}
+
+	content, err := ioutil.ReadFile(key)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid environment variable 'GITHUB_APP_PRIVATE_KEY'. failed to read the PEM file, %s", err)
+	}
+
+	return content, nil
+}
+
 func (self *Config) Owner() (string, error) {
 	repoArray, err := self.verifyRepository()
 	if err != nil {
@@ -47,10 +209,81 @@ func (self *Config) Repo() (string, error) {
 	return repoArray[1], nil
 }
 
+func (self *Config) CheckRunName() string {
+	return strings.TrimSpace(os.ExpandEnv(self.CheckRun.Name))
+}
+
+func (self *Config) CheckRunTitle() string {
+	return strings.TrimSpace(os.ExpandEnv(self.CheckRun.Title))
+}
+
 func (self *Config) TestResult() string {
 	return self.Workspace + self.TestResultFile
 }
 
+// TestResults resolves TEST_RESULT to the test result files in the workspace. TEST_RESULT can be
+// a file, a directory, a glob pattern or a comma or newline separated list of them.
+func (self *Config) TestResults() ([]string, error) {
+	entries := strings.FieldsFunc(self.TestResultFile, func(char rune) bool {
+		return char == ',' || char == '\n'
+	})
+
+	var results []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		files, err := self.resolveTestResult(self.Workspace + entry)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid environment variable 'TEST_RESULT'. failed to resolve '%s' because: %s", entry, err)
+		}
+		results = append(results, files...)
+	}
+
+	return results, nil
+}
+
+// testResultExtensions the extensions of the test result files looked up in a directory
+var testResultExtensions = []string{".xml", ".json", ".txt", ".log"}
+
+func (self *Config) resolveTestResult(path string) ([]string, error) {
+	if strings.ContainsAny(path, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+
+		// Keep the pattern so the missing report shows up when parsing it
+		if len(matches) == 0 {
+			return []string{path}, nil
+		}
+
+		return matches, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	fileInfos, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Other files next to the reports, e.g. a coverage profile, aren't test results
+	var files []string
+	for _, fileInfo := range fileInfos {
+		if fileInfo.Mode().IsRegular() && contains(testResultExtensions, strings.ToLower(filepath.Ext(fileInfo.Name()))) {
+			files = append(files, filepath.Join(path, fileInfo.Name()))
+		}
+	}
+
+	return files, nil
+}
+
 func (self *Config) verifyRepository() ([]string, error) {
 	repoArray := strings.Split(self.GitHub.Repository, "/")
 	if len(repoArray) != 2 {
@@ -0,0 +1,3 @@
+module example.com/svc/tools
+
+go 1.21
@@ -4,6 +4,12 @@ const (
 	// MediaTypeApplicationJSON media type: application/json
 	MediaTypeApplicationJSON = "application/json"
 
+	// MediaTypeGitHubV3JSON media type: application/vnd.github.v3+json
+	MediaTypeGitHubV3JSON = "application/vnd.github.v3+json"
+
 	// MediaTypeGitHubAntiopePreviewJSON media type: application/vnd.github.antiope-preview+json
 	MediaTypeGitHubAntiopePreviewJSON = "application/vnd.github.antiope-preview+json"
+
+	// MediaTypeGitHubMachineManPreviewJSON media type: application/vnd.github.machine-man-preview+json
+	MediaTypeGitHubMachineManPreviewJSON = "application/vnd.github.machine-man-preview+json"
 )
@@ -1,6 +1,7 @@
 package httpconst
 
 const (
+	MethodGet   = "GET"
 	MethodPost  = "POST"
 	MethodPatch = "PATCH"
 )
@@ -0,0 +1,96 @@
+package httputil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ClientOptions how the HTTP client reaches the GitHub API, GitHub Enterprise Server is often behind
+// a corporate proxy and signed by an internal CA.
+type ClientOptions struct {
+	Timeout time.Duration
+	// CABundle the path of the PEM file of the CAs trusted in addition to the system ones
+	CABundle string
+	// Proxy the URL of the proxy, HTTPS_PROXY and NO_PROXY are used when it's empty
+	Proxy string
+	// ClientCert and ClientKey the paths of the PEM files of the client certificate
+	ClientCert string
+	ClientKey  string
+}
+
+// NewClient creates an HTTP client by the options
+func NewClient(options ClientOptions) (*http.Client, error) {
+	// The same settings as http.DefaultTransport
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	if options.Proxy != "" {
+		proxyURL, err := url.Parse(options.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid proxy URL '%s' because: %s", options.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := newTLSConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Timeout:   options.Timeout,
+		Transport: transport,
+	}, nil
+}
+
+func newTLSConfig(options ClientOptions) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if options.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		content, err := ioutil.ReadFile(options.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read the CA bundle because: %s", err)
+		}
+
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("No certificate found in the CA bundle '%s'", options.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if options.ClientCert != "" || options.ClientKey != "" {
+		if options.ClientCert == "" || options.ClientKey == "" {
+			return nil, errors.New("Both the client certificate and its key must be set")
+		}
+
+		cert, err := tls.LoadX509KeyPair(options.ClientCert, options.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load the client certificate because: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
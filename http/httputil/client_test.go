@@ -0,0 +1,161 @@
+package httputil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestPEM writes the PEM blocks to a file in the directory and returns its path
+func writeTestPEM(test *testing.T, dir string, name string, blocks ...*pem.Block) string {
+	file := filepath.Join(dir, name)
+	var content []byte
+	for _, block := range blocks {
+		content = append(content, pem.EncodeToMemory(block)...)
+	}
+	if err := ioutil.WriteFile(file, content, 0600); err != nil {
+		test.Fatal(err)
+	}
+
+	return file
+}
+
+// newTestClientCert creates a self-signed client certificate and returns the paths of the certificate and its key
+func newTestClientCert(test *testing.T, dir string) (*x509.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "runner"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		test.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		test.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	return cert, writeTestPEM(test, dir, "client.pem", &pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		writeTestPEM(test, dir, "client-key.pem", &pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+func newTestDir(test *testing.T) string {
+	dir, err := ioutil.TempDir("", "client")
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	return dir
+}
+
+func Test_creating_a_client_with_the_CA_bundle_of_the_server_trusts_the_server(test *testing.T) {
+	dir := newTestDir(test)
+	defer os.RemoveAll(dir)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+	CABundle := writeTestPEM(test, dir, "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	client, err := NewClient(ClientOptions{CABundle: CABundle})
+	assert.NoError(test, err)
+	resp, err := client.Get(server.URL)
+
+	assert.NoError(test, err)
+	assert.Equal(test, 200, resp.StatusCode)
+}
+
+func Test_creating_a_client_without_the_CA_bundle_of_the_server_distrusts_the_server(test *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	client, err := NewClient(ClientOptions{})
+	assert.NoError(test, err)
+	_, err = client.Get(server.URL)
+
+	assert.Error(test, err)
+}
+
+func Test_creating_a_client_with_a_client_certificate_authenticates_to_the_server(test *testing.T) {
+	dir := newTestDir(test)
+	defer os.RemoveAll(dir)
+	cert, clientCert, clientKey := newTestClientCert(test, dir)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(test, "runner", req.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+	CABundle := writeTestPEM(test, dir, "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	client, err := NewClient(ClientOptions{
+		CABundle:   CABundle,
+		ClientCert: clientCert,
+		ClientKey:  clientKey,
+	})
+	assert.NoError(test, err)
+	resp, err := client.Get(server.URL)
+
+	assert.NoError(test, err)
+	assert.Equal(test, 200, resp.StatusCode)
+}
+
+func Test_creating_a_client_with_a_proxy_sends_the_requests_through_the_proxy(test *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		proxied = append(proxied, req.URL.String())
+	}))
+	defer proxy.Close()
+
+	client, err := NewClient(ClientOptions{Proxy: proxy.URL})
+	assert.NoError(test, err)
+	resp, err := client.Get("http://github.example.com/api/v3/meta")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 200, resp.StatusCode)
+	assert.Equal(test, []string{"http://github.example.com/api/v3/meta"}, proxied)
+}
+
+func Test_creating_a_client_with_a_client_certificate_without_its_key_returns_an_error(test *testing.T) {
+	_, err := NewClient(ClientOptions{ClientCert: "client.pem"})
+
+	assert.EqualError(test, err, "Both the client certificate and its key must be set")
+}
+
+func Test_creating_a_client_with_a_CA_bundle_of_no_certificate_returns_an_error(test *testing.T) {
+	dir := newTestDir(test)
+	defer os.RemoveAll(dir)
+	CABundle := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(CABundle, []byte("not a certificate"), 0600)
+
+	_, err := NewClient(ClientOptions{CABundle: CABundle})
+
+	assert.Error(test, err)
+}
@@ -0,0 +1,166 @@
+package httputil
+
+import (
+	"bytes"
+	"context"
+	"elb2c/gh-action/http/httpconst"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy how SendRequestWithRetry retries failed requests. Requests are retried on network errors,
+// 5xx HTTP status codes and rate limits, a zero policy sends a request only once. A POST request isn't
+// idempotent, it's only retried on rate limits since it may have taken effect otherwise.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+
+	sleep func(time.Duration)
+	now   func() time.Time
+}
+
+// DefaultRetryPolicy the retry policy of the GitHub API calls
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    time.Minute,
+}
+
+// SendRequestWithRetry sends a request like SendRequest and retries it with exponential backoff and jitter.
+// The Retry-After and X-RateLimit-Reset headers decide the delay when the API is rate limited.
+func SendRequestWithRetry(ctx context.Context, client *http.Client, URL string, method string,
+	header http.Header, reqBody io.Reader, policy RetryPolicy) (*Response, error) {
+
+	if reqBody == nil {
+		return nil, errors.New("HTTP request body must not be nil")
+	}
+
+	// The body is sent again on every attempt
+	body, err := ioutil.ReadAll(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := SendRequest(ctx, client, URL, method, header, bytes.NewReader(body))
+		if attempt >= policy.MaxAttempts || ctx.Err() != nil {
+			return resp, err
+		}
+
+		delay, retry := policy.retryDelay(attempt, method, resp, err)
+		if !retry {
+			return resp, err
+		}
+
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = "HTTP status code " + strconv.Itoa(resp.StatusCode)
+		}
+		log.Printf("Attempt %d/%d of %s %s failed because: %s. Retry in %s\n",
+			attempt, policy.MaxAttempts, method, URL, reason, delay)
+		if err := policy.wait(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (self *RetryPolicy) retryDelay(attempt int, method string, resp *Response, err error) (time.Duration, bool) {
+	idempotent := method != httpconst.MethodPost
+	if err != nil {
+		// Only the errors of sending the request are transient
+		if _, ok := err.(*url.Error); !ok || !idempotent {
+			return 0, false
+		}
+
+		return self.backoff(attempt), true
+	}
+
+	retryAfter := resp.Header.Get("Retry-After")
+	rateLimitExceeded := resp.Header.Get("X-RateLimit-Remaining") == "0"
+	rateLimited := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && (retryAfter != "" || rateLimitExceeded))
+	if !rateLimited && (resp.StatusCode < 500 || !idempotent) {
+		return 0, false
+	}
+
+	if retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return self.limit(time.Duration(seconds) * time.Second)
+		}
+
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return self.limit(date.Sub(self.currentTime()))
+		}
+	}
+
+	if rateLimitExceeded {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return self.limit(time.Unix(reset, 0).Sub(self.currentTime()))
+		}
+	}
+
+	return self.backoff(attempt), true
+}
+
+// backoff doubles the delay on every attempt, the jitter spreads the retries of concurrent jobs
+func (self *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := self.BaseDelay << uint(attempt-1)
+	if delay <= 0 || delay > self.MaxDelay {
+		delay = self.MaxDelay
+	}
+
+	if delay <= 1 {
+		return delay
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)))
+}
+
+// limit gives up retrying when the API asks to wait longer than the maximum delay
+func (self *RetryPolicy) limit(delay time.Duration) (time.Duration, bool) {
+	if delay < 0 {
+		delay = 0
+	}
+
+	if delay > self.MaxDelay {
+		return 0, false
+	}
+
+	return delay, true
+}
+
+// wait waits for the next attempt, the context cancels the waiting
+func (self *RetryPolicy) wait(ctx context.Context, delay time.Duration) error {
+	if self.sleep != nil {
+		self.sleep(delay)
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (self *RetryPolicy) currentTime() time.Time {
+	if self.now != nil {
+		return self.now()
+	}
+
+	return time.Now()
+}
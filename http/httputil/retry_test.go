@@ -0,0 +1,247 @@
+package httputil
+
+import (
+	"bytes"
+	"context"
+	"elb2c/gh-action/http/httpconst"
+	"elb2c/gh-action/testutil"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRetryPolicy(delays *[]time.Duration) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Second,
+		MaxDelay:    time.Minute,
+		sleep: func(delay time.Duration) {
+			*delays = append(*delays, delay)
+		},
+		now: func() time.Time {
+			return time.Unix(1565000000, 0)
+		},
+	}
+}
+
+func newTestResponse(statusCode int, header http.Header) *http.Response {
+	return &http.Response{
+		StatusCode: statusCode,
+		Header:     header,
+		Body:       ioutil.NopCloser(bytes.NewBufferString("response body")),
+	}
+}
+
+func Test_sending_a_request_with_retry_assuming_a_5xx_status_code_retries_with_backoff(test *testing.T) {
+	var delays []time.Duration
+	var bodies []string
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		bodies = append(bodies, string(testutil.ToBytes(req.Body)))
+		if len(bodies) < 3 {
+			return newTestResponse(502, make(http.Header))
+		}
+
+		return newTestResponse(200, make(http.Header))
+	})
+
+	result, err := SendRequestWithRetry(context.Background(), client, "http://test.local/api/1", httpconst.MethodPatch,
+		make(http.Header), bytes.NewBufferString("request body"), newTestRetryPolicy(&delays))
+
+	assert.NoError(test, err)
+	assert.Equal(test, 200, result.StatusCode)
+	assert.Equal(test, []string{"request body", "request body", "request body"}, bodies)
+	assert.Equal(test, 2, len(delays))
+	assert.True(test, delays[0] >= 500*time.Millisecond && delays[0] <= time.Second)
+	assert.True(test, delays[1] >= time.Second && delays[1] <= 2*time.Second)
+}
+
+func Test_sending_a_request_with_retry_assuming_a_network_error_retries_the_request(test *testing.T) {
+	var delays []time.Duration
+	attempts := 0
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		attempts++
+		if attempts == 1 {
+			// The client fails without a response
+			return nil
+		}
+
+		return newTestResponse(200, make(http.Header))
+	})
+
+	result, err := SendRequestWithRetry(context.Background(), client, "http://test.local/api/1", httpconst.MethodPatch,
+		make(http.Header), bytes.NewBufferString("request body"), newTestRetryPolicy(&delays))
+
+	assert.NoError(test, err)
+	assert.Equal(test, 200, result.StatusCode)
+	assert.Equal(test, 2, attempts)
+	assert.Equal(test, 1, len(delays))
+}
+
+func Test_sending_a_POST_request_with_retry_assuming_a_5xx_status_code_returns_the_response_without_retrying(test *testing.T) {
+	var delays []time.Duration
+	attempts := 0
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		attempts++
+		return newTestResponse(502, make(http.Header))
+	})
+
+	result, err := SendRequestWithRetry(context.Background(), client, "http://test.local/api/1", httpconst.MethodPost,
+		make(http.Header), bytes.NewBufferString("request body"), newTestRetryPolicy(&delays))
+
+	assert.NoError(test, err)
+	assert.Equal(test, 502, result.StatusCode)
+	assert.Equal(test, 1, attempts)
+	assert.Empty(test, delays)
+}
+
+func Test_sending_a_POST_request_with_retry_assuming_a_network_error_returns_the_error_without_retrying(test *testing.T) {
+	var delays []time.Duration
+	attempts := 0
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		attempts++
+		return nil
+	})
+
+	_, err := SendRequestWithRetry(context.Background(), client, "http://test.local/api/1", httpconst.MethodPost,
+		make(http.Header), bytes.NewBufferString("request body"), newTestRetryPolicy(&delays))
+
+	assert.Error(test, err)
+	assert.Equal(test, 1, attempts)
+	assert.Empty(test, delays)
+}
+
+func Test_sending_a_request_with_retry_assuming_a_retry_after_header_waits_the_given_seconds(test *testing.T) {
+	var delays []time.Duration
+	attempts := 0
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		attempts++
+		if attempts == 1 {
+			header := make(http.Header)
+			header.Add("Retry-After", "5")
+			return newTestResponse(403, header)
+		}
+
+		return newTestResponse(200, make(http.Header))
+	})
+
+	result, err := SendRequestWithRetry(context.Background(), client, "http://test.local/api/1", httpconst.MethodPost,
+		make(http.Header), bytes.NewBufferString("request body"), newTestRetryPolicy(&delays))
+
+	assert.NoError(test, err)
+	assert.Equal(test, 200, result.StatusCode)
+	assert.Equal(test, []time.Duration{5 * time.Second}, delays)
+}
+
+func Test_sending_a_request_with_retry_assuming_an_exceeded_rate_limit_waits_until_the_reset(test *testing.T) {
+	var delays []time.Duration
+	attempts := 0
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		attempts++
+		if attempts == 1 {
+			header := make(http.Header)
+			header.Add("X-RateLimit-Remaining", "0")
+			header.Add("X-RateLimit-Reset", strconv.Itoa(1565000030))
+			return newTestResponse(403, header)
+		}
+
+		return newTestResponse(200, make(http.Header))
+	})
+
+	result, err := SendRequestWithRetry(context.Background(), client, "http://test.local/api/1", httpconst.MethodPost,
+		make(http.Header), bytes.NewBufferString("request body"), newTestRetryPolicy(&delays))
+
+	assert.NoError(test, err)
+	assert.Equal(test, 200, result.StatusCode)
+	assert.Equal(test, []time.Duration{30 * time.Second}, delays)
+}
+
+func Test_sending_a_request_with_retry_assuming_a_rate_limit_reset_beyond_the_max_delay_returns_the_response(test *testing.T) {
+	var delays []time.Duration
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		header := make(http.Header)
+		header.Add("X-RateLimit-Remaining", "0")
+		header.Add("X-RateLimit-Reset", strconv.Itoa(1565003600))
+		return newTestResponse(403, header)
+	})
+
+	result, err := SendRequestWithRetry(context.Background(), client, "http://test.local/api/1", httpconst.MethodPost,
+		make(http.Header), bytes.NewBufferString("request body"), newTestRetryPolicy(&delays))
+
+	assert.NoError(test, err)
+	assert.Equal(test, 403, result.StatusCode)
+	assert.Equal(test, 0, len(delays))
+}
+
+func Test_sending_a_request_with_retry_assuming_a_4xx_status_code_returns_the_response_without_retrying(test *testing.T) {
+	var delays []time.Duration
+	attempts := 0
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		attempts++
+		return newTestResponse(422, make(http.Header))
+	})
+
+	result, err := SendRequestWithRetry(context.Background(), client, "http://test.local/api/1", httpconst.MethodPost,
+		make(http.Header), bytes.NewBufferString("request body"), newTestRetryPolicy(&delays))
+
+	assert.NoError(test, err)
+	assert.Equal(test, 422, result.StatusCode)
+	assert.Equal(test, 1, attempts)
+	assert.Equal(test, 0, len(delays))
+}
+
+func Test_sending_a_request_with_retry_assuming_every_attempt_failing_returns_the_last_response(test *testing.T) {
+	var delays []time.Duration
+	attempts := 0
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		attempts++
+		return newTestResponse(500, make(http.Header))
+	})
+
+	result, err := SendRequestWithRetry(context.Background(), client, "http://test.local/api/1", httpconst.MethodPatch,
+		make(http.Header), bytes.NewBufferString("request body"), newTestRetryPolicy(&delays))
+
+	assert.NoError(test, err)
+	assert.Equal(test, 500, result.StatusCode)
+	assert.Equal(test, 3, attempts)
+	assert.Equal(test, 2, len(delays))
+}
+
+func Test_sending_a_request_with_a_zero_retry_policy_sends_the_request_once(test *testing.T) {
+	attempts := 0
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		attempts++
+		return newTestResponse(500, make(http.Header))
+	})
+
+	result, err := SendRequestWithRetry(context.Background(), client, "http://test.local/api/1", httpconst.MethodPost,
+		make(http.Header), bytes.NewBufferString("request body"), RetryPolicy{})
+
+	assert.NoError(test, err)
+	assert.Equal(test, 500, result.StatusCode)
+	assert.Equal(test, 1, attempts)
+}
+
+func Test_sending_a_request_with_retry_assuming_the_context_expiring_while_waiting_returns_the_context_error(test *testing.T) {
+	attempts := 0
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		attempts++
+		return newTestResponse(503, make(http.Header))
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	policy := RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Minute,
+		MaxDelay:    time.Minute,
+	}
+
+	_, err := SendRequestWithRetry(ctx, client, "http://test.local/api/1", httpconst.MethodPatch,
+		make(http.Header), bytes.NewBufferString("request body"), policy)
+
+	assert.Equal(test, context.DeadlineExceeded, err)
+	assert.Equal(test, 1, attempts)
+}
@@ -1,6 +1,7 @@
 package httputil
 
 import (
+	"context"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -13,7 +14,13 @@ type Response struct {
 	Body       []byte
 }
 
-func SendRequest(client *http.Client, URL string, method string, header http.Header, reqBody io.Reader) (*Response, error) {
+func SendRequest(ctx context.Context, client *http.Client, URL string, method string,
+	header http.Header, reqBody io.Reader) (*Response, error) {
+
+	if ctx == nil {
+		return nil, errors.New("Context must not be nil")
+	}
+
 	if client == nil {
 		return nil, errors.New("HTTP client must not be nil")
 	}
@@ -34,6 +41,7 @@ func SendRequest(client *http.Client, URL string, method string, header http.Hea
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
 	req.Header = header
 
 	// Send the request
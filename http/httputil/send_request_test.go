@@ -2,6 +2,7 @@ package httputil
 
 import (
 	"bytes"
+	"context"
 	"elb2c/gh-action/http/httpconst"
 	"elb2c/gh-action/testutil"
 	"io/ioutil"
@@ -37,7 +38,7 @@ func Test_sending_a_request_with_an_URL_a_method_and_headers_returns_a_HTTP_stat
 		}
 	})
 
-	result, err := SendRequest(client, reqURL, reqMethod, reqHeder, nil)
+	result, err := SendRequest(context.Background(), client, reqURL, reqMethod, reqHeder, nil)
 
 	assert.NoError(test, err)
 
@@ -45,3 +46,16 @@ func Test_sending_a_request_with_an_URL_a_method_and_headers_returns_a_HTTP_stat
 	assert.Equal(test, respHeader, result.Header)
 	assert.Equal(test, respBody, result.Body)
 }
+
+func Test_sending_a_request_with_a_cancelled_context_returns_an_error(test *testing.T) {
+	client := testutil.NewTestHTTPClient(func(req *http.Request) *http.Response {
+		<-req.Context().Done()
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := SendRequest(ctx, client, "http://test.local/api/1", httpconst.MethodPost, make(http.Header), nil)
+
+	assert.Error(test, err)
+}
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
+	"elb2c/gh-action/api"
+	"elb2c/gh-action/api/auth"
 	"elb2c/gh-action/api/checkrun"
 	"elb2c/gh-action/config"
+	"elb2c/gh-action/http/httputil"
 	"elb2c/gh-action/service"
+	"elb2c/gh-action/workflow"
 	"fmt"
-	"net/http"
+	"log"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
@@ -16,15 +23,78 @@ func main() {
 		os.Exit(1)
 	}
 
-	parser := service.NewTestResultParser()
+	if err := service.VerifyTestResultFormat(&cfg); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	parser := service.NewTestResultFormatDetector(&cfg)
 
-	httpClient := &http.Client{}
-	checkRunCreator := checkrun.NewCreator(httpClient, cfg.GitHub.URL, &cfg)
-	checkRunUpdator := checkrun.NewUpdater(httpClient, cfg.GitHub.URL, &cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
 
-	annotator := service.NewTestFailureAnnotator(&cfg, parser, checkRunCreator, checkRunUpdator)
-	if err := annotator.Annotate(); err != nil {
+	// Cancel the annotation on SIGINT and SIGTERM, so the check run is completed before exiting
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			log.Printf("Received %s, cancelling the annotation\n", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var creator checkrun.Creator
+	var updater checkrun.Updater
+	var reporter *workflow.CommandReporter
+	if cfg.UseWorkflowCommands() {
+		// The workflow commands need neither the network nor a token
+		log.Println("Annotate the test failures by workflow commands")
+		reporter = workflow.NewReporter(os.Stdout, &cfg)
+		creator, updater = reporter, reporter
+	} else {
+		creator, updater, err = newCheckRunReporter(ctx, &cfg)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	annotator := service.NewTestFailureAnnotator(&cfg, parser, creator, updater)
+	if err := annotator.Annotate(ctx); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+
+	// Without a check run only the step can fail the workflow
+	if reporter != nil && reporter.Failed() {
+		os.Exit(1)
+	}
+}
+
+// newCheckRunReporter creates the creator and the updater of the check run on the GitHub server
+func newCheckRunReporter(ctx context.Context, cfg *config.Config) (checkrun.Creator, checkrun.Updater, error) {
+	httpClient, err := httputil.NewClient(httputil.ClientOptions{
+		Timeout:    cfg.RequestTimeout,
+		CABundle:   cfg.Connection.CABundle,
+		Proxy:      cfg.Connection.Proxy,
+		ClientCert: cfg.Connection.ClientCert,
+		ClientKey:  cfg.Connection.ClientKey,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	apiURL := cfg.APIURL()
+	server := api.DetectServer(ctx, httpClient, apiURL)
+	log.Printf("Annotate the test failures on %s\n", server)
+
+	tokens, err := auth.NewTokenSource(httpClient, apiURL, cfg, server)
+	if err != nil {
+		return nil, nil, err
+	}
+	checkRunCreator := checkrun.NewCreator(httpClient, apiURL, cfg, tokens, server)
+	checkRunUpdator := checkrun.NewUpdater(httpClient, apiURL, cfg, tokens, server)
+
+	return checkRunCreator, checkRunUpdator, nil
 }
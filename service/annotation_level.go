@@ -0,0 +1,37 @@
+package service
+
+import (
+	"elb2c/gh-action/config"
+	"path"
+)
+
+// levelFailure the annotation level of the failures no rule matches
+const levelFailure = "failure"
+
+// annotationLevel returns the level of the first rule matching the failure
+func annotationLevel(rules []config.AnnotationRule, failure TestFailure) string {
+	for _, rule := range rules {
+		if matchesRule(rule, failure) {
+			return rule.Level
+		}
+	}
+
+	return levelFailure
+}
+
+func matchesRule(rule config.AnnotationRule, failure TestFailure) bool {
+	if rule.Package != nil && !rule.Package.MatchString(failure.Package) &&
+		!rule.Package.MatchString(path.Dir(failure.File)) {
+		return false
+	}
+
+	if rule.Test != nil && !rule.Test.MatchString(failure.Name) {
+		return false
+	}
+
+	if rule.Category != "" && rule.Category != failure.Category {
+		return false
+	}
+
+	return true
+}
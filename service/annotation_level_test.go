@@ -0,0 +1,55 @@
+package service
+
+import (
+	"elb2c/gh-action/config"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_passing_no_rules_returns_the_failure_level(test *testing.T) {
+	result := annotationLevel(nil, TestFailure{Name: "TestA", File: "a_test.go"})
+
+	assert.Equal(test, "failure", result)
+}
+
+func Test_passing_a_failure_in_a_matched_directory_returns_the_level_of_the_rule(test *testing.T) {
+	rules := []config.AnnotationRule{
+		{Level: "warning", Package: regexp.MustCompile(`^e2e(/.*)?$`)},
+	}
+
+	assert.Equal(test, "warning", annotationLevel(rules, TestFailure{File: "e2e/checkout/checkout_test.go"}))
+	assert.Equal(test, "failure", annotationLevel(rules, TestFailure{File: "cart/cart_test.go"}))
+}
+
+func Test_passing_a_failure_in_a_matched_package_returns_the_level_of_the_rule(test *testing.T) {
+	rules := []config.AnnotationRule{
+		{Level: "notice", Package: regexp.MustCompile(`^example\.com/app/e2e$`)},
+	}
+
+	result := annotationLevel(rules, TestFailure{Package: "example.com/app/e2e", File: "checkout_test.go"})
+
+	assert.Equal(test, "notice", result)
+}
+
+func Test_passing_a_failure_matching_some_selectors_of_a_rule_returns_the_failure_level(test *testing.T) {
+	rules := []config.AnnotationRule{
+		{Level: "warning", Test: regexp.MustCompile(`^TestIntegration`), Category: "timeout"},
+	}
+
+	result := annotationLevel(rules, TestFailure{Name: "TestIntegrationCheckout", Category: "panic"})
+
+	assert.Equal(test, "failure", result)
+}
+
+func Test_passing_a_failure_matching_several_rules_returns_the_level_of_the_first_rule(test *testing.T) {
+	rules := []config.AnnotationRule{
+		{Level: "notice", Category: "timeout"},
+		{Level: "warning", Test: regexp.MustCompile(`^TestIntegration`)},
+	}
+
+	result := annotationLevel(rules, TestFailure{Name: "TestIntegrationCheckout", Category: "timeout"})
+
+	assert.Equal(test, "notice", result)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"elb2c/gh-action/config"
+	"fmt"
+)
+
+// verdict how the test failures conclude the check run
+type verdict struct {
+	conclusion string
+	// tolerated whether the test failures are within the allowed failures
+	tolerated bool
+	failed    int
+	total     int
+}
+
+// conclude decides the conclusion of the test failures by the conclusion policy. Failing several
+// assertions counts as one failing test. The tests fail when the failing tests exceed the allowed count
+// and, when it's set, the allowed percentage of the tests.
+func conclude(policy config.Conclusion, failures []TestFailure, packages []PackageStatistics) verdict {
+	// Tests of different packages may share a name
+	names := make(map[testKey]bool)
+	for _, failure := range failures {
+		names[testKey{pkg: failure.Package, name: failure.Name}] = true
+	}
+
+	total := 0
+	for _, pkg := range packages {
+		total += pkg.Total()
+	}
+
+	result := verdict{conclusion: "success", failed: len(names), total: total}
+	if result.total < result.failed {
+		// The report has no statistics, the failing tests are all the known tests
+		result.total = result.failed
+	}
+
+	if result.failed == 0 {
+		return result
+	}
+
+	if result.failed <= policy.AllowedFailures || !exceedsPercentage(policy, result) {
+		result.tolerated = true
+		return result
+	}
+
+	result.conclusion = policy.OnFailure
+	if result.conclusion == "" {
+		result.conclusion = "failure"
+	}
+
+	return result
+}
+
+func exceedsPercentage(policy config.Conclusion, result verdict) bool {
+	if policy.AllowedFailurePercentage <= 0 {
+		return true
+	}
+
+	return float64(result.failed)*100 > policy.AllowedFailurePercentage*float64(result.total)
+}
+
+// headline the first line of the summary
+func (self verdict) headline(annotations int) string {
+	headline := fmt.Sprintf("%d test failure(s) found", annotations)
+	if self.tolerated {
+		headline = fmt.Sprintf("%s, %d of %d test(s) failed within the allowed failures", headline, self.failed, self.total)
+	}
+
+	return headline
+}
+
+// concludeMissingReport the conclusion when no test result is found
+func concludeMissingReport(policy config.Conclusion) string {
+	if policy.OnMissingReport == "" {
+		return "failure"
+	}
+
+	return policy.OnMissingReport
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"elb2c/gh-action/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_passing_no_test_failures_returns_success(test *testing.T) {
+	result := conclude(config.Conclusion{}, nil, []PackageStatistics{{Passed: 3}})
+
+	assert.Equal(test, "success", result.conclusion)
+	assert.False(test, result.tolerated)
+}
+
+func Test_passing_test_failures_with_the_default_policy_returns_failure(test *testing.T) {
+	failures := []TestFailure{{Name: "TestA"}}
+
+	result := conclude(config.Conclusion{}, failures, []PackageStatistics{{Passed: 3, Failed: 1}})
+
+	assert.Equal(test, "failure", result.conclusion)
+	assert.False(test, result.tolerated)
+}
+
+func Test_passing_test_failures_with_the_neutral_policy_returns_neutral(test *testing.T) {
+	failures := []TestFailure{{Name: "TestA"}}
+
+	result := conclude(config.Conclusion{OnFailure: "neutral"}, failures, nil)
+
+	assert.Equal(test, "neutral", result.conclusion)
+}
+
+func Test_passing_test_failures_within_the_allowed_failures_returns_success(test *testing.T) {
+	// Several failing assertions of a test count as one failing test
+	failures := []TestFailure{{Name: "TestA", Line: 1}, {Name: "TestA", Line: 2}, {Name: "TestB"}}
+
+	result := conclude(config.Conclusion{AllowedFailures: 2}, failures, []PackageStatistics{{Passed: 8, Failed: 2}})
+
+	assert.Equal(test, "success", result.conclusion)
+	assert.True(test, result.tolerated)
+	assert.Equal(test, "3 test failure(s) found, 2 of 10 test(s) failed within the allowed failures", result.headline(3))
+}
+
+func Test_passing_test_failures_over_the_allowed_failures_returns_failure(test *testing.T) {
+	failures := []TestFailure{{Name: "TestA"}, {Name: "TestB"}, {Name: "TestC"}}
+
+	result := conclude(config.Conclusion{AllowedFailures: 2}, failures, []PackageStatistics{{Passed: 7, Failed: 3}})
+
+	assert.Equal(test, "failure", result.conclusion)
+	assert.Equal(test, "3 test failure(s) found", result.headline(3))
+}
+
+func Test_passing_failing_tests_of_the_same_name_in_different_packages_counts_each_of_them(test *testing.T) {
+	failures := []TestFailure{{Package: "example.com/app/cart", Name: "TestSave"}, {Package: "example.com/app/user", Name: "TestSave"}}
+
+	result := conclude(config.Conclusion{AllowedFailures: 1}, failures, []PackageStatistics{{Passed: 8, Failed: 2}})
+
+	assert.Equal(test, "failure", result.conclusion)
+	assert.Equal(test, 2, result.failed)
+}
+
+func Test_passing_test_failures_within_the_allowed_percentage_returns_success(test *testing.T) {
+	failures := []TestFailure{{Name: "TestA"}, {Name: "TestB"}}
+
+	result := conclude(config.Conclusion{AllowedFailurePercentage: 5}, failures, []PackageStatistics{{Passed: 38, Failed: 2}})
+
+	assert.Equal(test, "success", result.conclusion)
+	assert.True(test, result.tolerated)
+}
+
+func Test_passing_test_failures_over_the_allowed_count_and_percentage_returns_failure(test *testing.T) {
+	failures := []TestFailure{{Name: "TestA"}, {Name: "TestB"}, {Name: "TestC"}}
+	policy := config.Conclusion{AllowedFailures: 1, AllowedFailurePercentage: 5}
+
+	result := conclude(policy, failures, []PackageStatistics{{Passed: 37, Failed: 3}})
+
+	assert.Equal(test, "failure", result.conclusion)
+}
+
+func Test_passing_test_failures_without_statistics_counts_the_failing_tests_as_all_tests(test *testing.T) {
+	failures := []TestFailure{{Name: "TestA"}}
+
+	result := conclude(config.Conclusion{AllowedFailurePercentage: 50}, failures, nil)
+
+	assert.Equal(test, "failure", result.conclusion)
+	assert.Equal(test, 1, result.total)
+}
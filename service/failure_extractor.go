@@ -0,0 +1,590 @@
+package service
+
+import (
+	"elb2c/gh-action/config"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	regexErrorTrace  = regexp.MustCompile(`Error Trace:(\s+)(\S+)\:(\d+)`)
+	regexTraceFrame  = regexp.MustCompile(`^\s*(\S+)\:(\d+)\s*$`)
+	regexErrorDtails = regexp.MustCompile(`Error:(\s+)(.*)(\s.*)?(\s.*)?`)
+	regexLogPrefix   = regexp.MustCompile(`^\s*([\w\-.]+\.go):(\d+): ?(.*)$`)
+	regexPanic       = regexp.MustCompile(`(?m)^\s*panic: (.*?)(?: \[recovered[^\]]*\])?$`)
+	regexBuildError  = regexp.MustCompile(`(?m)^(\S+\.go):(\d+):\d+: (.*)$`)
+	regexStackFrame  = regexp.MustCompile(`^\s+(\S+\.go):(\d+)(?: \+0x[0-9a-f]+)?$`)
+	regexTestFunc    = regexp.MustCompile(`\.((?:Test|Benchmark|Example|Fuzz)\w*)([.(])`)
+	regexRunningTest = regexp.MustCompile(`^\s+(\S+) \(.*\)$`)
+)
+
+// How a test failed, annotation rules select the failures by them
+const (
+	categoryAssertion = "assertion"
+	categoryError     = "error"
+	categoryPanic     = "panic"
+	categoryTimeout   = "timeout"
+)
+
+// failureExtractor extracts the failing file, line and reason from the output of a failed test.
+// It's shared by all the test result parsers so every report format is annotated the same way.
+type failureExtractor struct {
+	workspace           string
+	repository          string
+	annotatePanicOrigin bool
+	modules             moduleResolver
+}
+
+// testKey identifies a test by its package import path and its full name
+type testKey struct {
+	pkg  string
+	name string
+}
+
+func newFailureExtractor(cfg *config.Config) failureExtractor {
+	if cfg == nil {
+		return failureExtractor{}
+	}
+
+	repository, _ := cfg.Repo()
+
+	return failureExtractor{
+		workspace:           strings.TrimSuffix(cfg.Workspace, "/"),
+		repository:          repository,
+		annotatePanicOrigin: cfg.AnnotatePanicOrigin,
+		modules:             newModuleResolver(cfg.Workspace),
+	}
+}
+
+// buildFailures builds the failures of a test from its output. A test failing without a file and line,
+// e.g. by t.FailNow(), gets a failure without a location, so it still fails the tests.
+func (self *failureExtractor) buildFailures(details string) []TestFailure {
+	if regexPanic.MatchString(details) {
+		if failures := self.buildPanicFailures(details); len(failures) > 0 {
+			return failures
+		}
+	}
+
+	// Failures reported by t.Error, t.Fatal and friends have no testify Error Trace
+	if !regexErrorTrace.MatchString(details) {
+		if failures := self.buildLogFailures(details); len(failures) > 0 {
+			return failures
+		}
+	}
+
+	var failures []TestFailure
+	for _, assertion := range self.splitAssertions(details) {
+		failure, err := self.buildFailure(assertion)
+		if err != nil {
+			log.Printf("Failed to locate a test failure because: %s\n", err)
+			continue
+		}
+		failures = append(failures, *failure)
+	}
+
+	if len(failures) == 0 {
+		failures = append(failures, TestFailure{
+			Reason:   findUnlocatedReason(details),
+			Category: categoryError,
+		})
+	}
+
+	return failures
+}
+
+// buildPackageFailures builds the failures of a package which failed without a failing test, e.g. by a
+// build failure or a timeout. The details are the output of the package and of its unfinished tests.
+func (self *failureExtractor) buildPackageFailures(pkg string, details string) []TestFailure {
+	failures := self.buildBuildFailures(details)
+	if len(failures) == 0 {
+		failures = self.buildFailures(details)
+		for i := range failures {
+			failures[i].File = self.resolvePath(pkg, failures[i].File)
+		}
+	}
+
+	for i := range failures {
+		if failures[i].Name == "" {
+			failures[i].Name = pkg
+		}
+		failures[i].Package = pkg
+		failures[i].Details = details
+	}
+
+	return failures
+}
+
+// buildBuildFailures builds a failure from every compiler error of a build failure. The compiler prints
+// the files relative to the directory `go test` runs in, which is assumed to be the repository.
+func (self *failureExtractor) buildBuildFailures(details string) []TestFailure {
+	var failures []TestFailure
+	for _, match := range regexBuildError.FindAllStringSubmatch(details, -1) {
+		lineNumber, err := strconv.Atoi(match[2])
+		if err != nil {
+			continue
+		}
+
+		file := path.Clean(match[1])
+		if filepath.IsAbs(file) {
+			relative, ok := self.relativePath(file)
+			if !ok {
+				continue
+			}
+			file = relative
+		}
+
+		failures = append(failures, TestFailure{
+			Line:     lineNumber,
+			File:     file,
+			Reason:   strings.TrimSpace(match[3]),
+			Category: categoryError,
+		})
+	}
+
+	return failures
+}
+
+// findUnlocatedReason returns the last line of the output which isn't printed by the testing package
+// itself, it tells the most about a failure without a location.
+func findUnlocatedReason(details string) string {
+	if match := regexPanic.FindStringSubmatch(details); match != nil {
+		return "panic: " + match[1]
+	}
+
+	reason := "The test failed without a message"
+	for _, line := range strings.Split(details, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "FAIL" || line == "PASS" || strings.HasPrefix(line, "=== ") || strings.HasPrefix(line, "--- ") {
+			continue
+		}
+		reason = line
+	}
+
+	return reason
+}
+
+// splitAssertions splits the details into one block per failed testify assertion, each block starts
+// with its own Error Trace.
+func (self *failureExtractor) splitAssertions(details string) []string {
+	indexes := regexErrorTrace.FindAllStringIndex(details, -1)
+	if len(indexes) == 0 {
+		return []string{details}
+	}
+
+	var assertions []string
+	for i, index := range indexes {
+		end := len(details)
+		if i+1 < len(indexes) {
+			end = indexes[i+1][0]
+		}
+		assertions = append(assertions, details[index[0]:end])
+	}
+
+	return assertions
+}
+
+func (self *failureExtractor) buildFailure(details string) (*TestFailure, error) {
+	lineNumber, err := self.findLineNumber(details)
+	if err != nil {
+		return nil, err
+	}
+
+	fileName, err := self.findFileName(details)
+	if err != nil {
+		return nil, err
+	}
+
+	reason, err := self.findReason(details)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TestFailure{
+		Line:     lineNumber,
+		File:     fileName,
+		Reason:   reason,
+		Category: categoryAssertion,
+	}, nil
+}
+
+// buildLogFailures builds a failure from the last `file_test.go:NN: message` block the testing package
+// prints, the indented lines following it belong to the same message. t.Log prints the same blocks as
+// t.Error, but a t.Fatal ends the test, so only the last block is annotated and the earlier blocks are
+// kept in its message.
+func (self *failureExtractor) buildLogFailures(details string) []TestFailure {
+	var blocks []string
+	var last *TestFailure
+	lines := strings.Split(details, "\n")
+	for i, line := range lines {
+		match := regexLogPrefix.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		lineNumber, err := strconv.Atoi(match[2])
+		if err != nil {
+			continue
+		}
+
+		message := []string{strings.TrimSpace(match[3])}
+		for _, next := range lines[i+1:] {
+			trimmed := strings.TrimSpace(next)
+			if regexLogPrefix.MatchString(next) || strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- ") {
+				break
+			}
+			message = append(message, trimmed)
+		}
+
+		if last != nil {
+			blocks = append(blocks, fmt.Sprintf("%s:%d: %s", last.File, last.Line, last.Reason))
+		}
+		last = &TestFailure{
+			Line:     lineNumber,
+			File:     match[1],
+			Reason:   strings.TrimSpace(strings.Join(message, "\n")),
+			Category: categoryError,
+		}
+	}
+
+	if last == nil {
+		return nil
+	}
+
+	if len(blocks) > 0 {
+		last.Reason += "\n\nEarlier output of the test:\n" + strings.Join(blocks, "\n")
+	}
+
+	return []TestFailure{*last}
+}
+
+// buildPanicFailures annotates the test frame of the repository closest to the panic, and optionally
+// the non-test frame of the repository where the panic originated.
+func (self *failureExtractor) buildPanicFailures(details string) []TestFailure {
+	message := "panic: " + regexPanic.FindStringSubmatch(details)[1]
+	if strings.HasPrefix(message, "panic: test timed out") {
+		return self.buildTimeoutFailures(details, message)
+	}
+
+	// Only the stack of the panicking goroutine matters
+	stack := ""
+	if stacks := splitGoroutines(details); len(stacks) > 0 {
+		stack = stacks[0]
+	}
+
+	var testFrame, originFrame *TestFailure
+	for _, line := range strings.Split(stack, "\n") {
+		frame := regexStackFrame.FindStringSubmatch(line)
+		if frame == nil {
+			continue
+		}
+
+		if _, ok := self.relativePath(frame[1]); !ok {
+			continue
+		}
+
+		lineNumber, err := strconv.Atoi(frame[2])
+		if err != nil {
+			continue
+		}
+
+		if strings.HasSuffix(frame[1], "_test.go") {
+			testFrame = &TestFailure{
+				Line:     lineNumber,
+				File:     frame[1],
+				Reason:   message,
+				Category: categoryPanic,
+			}
+			break
+		}
+
+		if originFrame == nil {
+			originFrame = &TestFailure{
+				Line:     lineNumber,
+				File:     frame[1],
+				Reason:   message + "\n\n" + stack,
+				Category: categoryPanic,
+			}
+		}
+	}
+
+	var failures []TestFailure
+	if testFrame != nil {
+		failures = append(failures, *testFrame)
+	}
+
+	if originFrame != nil && (self.annotatePanicOrigin || testFrame == nil) {
+		failures = append(failures, *originFrame)
+	}
+
+	return failures
+}
+
+// buildTimeoutFailures annotates the running tests of a test binary which timed out. The panicking
+// goroutine is the alarm of the testing package, so the test frames of the repository are looked up in
+// the stacks of all the goroutines, one failure per goroutine.
+func (self *failureExtractor) buildTimeoutFailures(details string, message string) []TestFailure {
+	all := findRunningTests(details)
+	running := filterRunningSubtests(all)
+	leaves := make(map[string]bool)
+	for _, name := range running {
+		leaves[name] = true
+	}
+
+	var failures []TestFailure
+	named := make(map[string]bool)
+	for _, stack := range splitGoroutines(details) {
+		lines := strings.Split(stack, "\n")
+		for i, line := range lines {
+			frame := regexStackFrame.FindStringSubmatch(line)
+			if frame == nil || !strings.HasSuffix(frame[1], "_test.go") {
+				continue
+			}
+
+			if _, ok := self.relativePath(frame[1]); !ok {
+				continue
+			}
+
+			lineNumber, err := strconv.Atoi(frame[2])
+			if err != nil {
+				continue
+			}
+
+			name := ""
+			if i > 0 {
+				if function := regexTestFunc.FindStringSubmatch(lines[i-1]); function != nil {
+					name = matchRunningTest(all, function[1], function[2] == ".")
+				}
+			}
+			// A test waiting for its subtests times out by them
+			if name != "" && !leaves[name] {
+				break
+			}
+			if name == "" && len(running) == 1 {
+				name = running[0]
+			}
+			named[name] = true
+
+			failures = append(failures, TestFailure{
+				Line:     lineNumber,
+				File:     frame[1],
+				Name:     name,
+				Reason:   message,
+				Category: categoryTimeout,
+			})
+			break
+		}
+	}
+
+	// The tests without a stack in the repository still timed out
+	for _, name := range running {
+		if !named[name] {
+			failures = append(failures, TestFailure{Name: name, Reason: message, Category: categoryTimeout})
+		}
+	}
+
+	return failures
+}
+
+// findRunningTests returns the tests the testing package lists as running when the test binary timed out
+func findRunningTests(details string) []string {
+	index := strings.Index(details, "running tests:\n")
+	if index < 0 {
+		return nil
+	}
+
+	var names []string
+	for _, line := range strings.Split(details[index+len("running tests:\n"):], "\n") {
+		match := regexRunningTest.FindStringSubmatch(line)
+		if match == nil {
+			break
+		}
+		names = append(names, match[1])
+	}
+
+	return names
+}
+
+// filterRunningSubtests drops the running tests which wait for their subtests, the same as filterFailedTests
+func filterRunningSubtests(running []string) (result []string) {
+	parents := make(map[string]bool)
+	for _, name := range running {
+		if index := strings.LastIndex(name, "/"); index > 0 {
+			parents[name[:index]] = true
+		}
+	}
+
+	for _, name := range running {
+		if !parents[name] {
+			result = append(result, name)
+		}
+	}
+
+	return
+}
+
+// matchRunningTest returns the running test of a test function. A subtest runs a closure of the function
+// of its top-level test in a goroutine of its own, the most nested subtest is the one which hangs.
+func matchRunningTest(running []string, function string, closure bool) string {
+	name := ""
+	for _, test := range running {
+		if !closure {
+			if test == function {
+				return test
+			}
+			continue
+		}
+
+		if strings.HasPrefix(test, function+"/") && len(test) > len(name) {
+			name = test
+		}
+	}
+
+	return name
+}
+
+// splitGoroutines splits a goroutine dump into the stacks of the goroutines
+func splitGoroutines(details string) []string {
+	index := strings.Index(details, "goroutine ")
+	if index < 0 {
+		return nil
+	}
+
+	return strings.Split(details[index:], "\n\n")
+}
+
+// resolvePath converts the file of a failure to a path relative to the root of the repository
+func (self *failureExtractor) resolvePath(pkg string, file string) string {
+	if file == "" {
+		return ""
+	}
+
+	if filepath.IsAbs(file) {
+		if relative, ok := self.relativePath(file); ok {
+			return relative
+		}
+		file = filepath.Base(file)
+	}
+
+	if pkg == "" {
+		return file
+	}
+
+	return path.Join(self.getDirectory(pkg), file)
+}
+
+// relativePath strips the workspace from an absolute path. The report may be produced outside of the
+// action's container, so the checkout directory of GitHub runners (.../<repository>/<repository>/) is
+// recognized too.
+func (self *failureExtractor) relativePath(file string) (string, bool) {
+	if self.workspace != "" && strings.HasPrefix(file, self.workspace+"/") {
+		return strings.TrimPrefix(file, self.workspace+"/"), true
+	}
+
+	if self.repository != "" {
+		checkout := "/" + self.repository + "/" + self.repository + "/"
+		if index := strings.Index(file, checkout); index >= 0 {
+			return file[index+len(checkout):], true
+		}
+	}
+
+	return "", false
+}
+
+// getDirectory returns the directory of a package, the last element of the import path is used
+// when the package belongs to none of the modules in the workspace.
+func (self *failureExtractor) getDirectory(className string) string {
+	if dir, ok := self.modules.resolve(className); ok {
+		return dir
+	}
+
+	array := strings.Split(className, "/")
+	return array[len(array)-1]
+}
+
+func (self *failureExtractor) findFileName(details string) (string, error) {
+	frame := self.findTraceFrame(details)
+	if frame == nil {
+		return "", errors.New("No file name matches")
+	}
+	return frame.file, nil
+}
+
+func (self *failureExtractor) findLineNumber(details string) (int, error) {
+	frame := self.findTraceFrame(details)
+	if frame == nil {
+		return 0, errors.New("No line number matches")
+	}
+	return strconv.Atoi(frame.line)
+}
+
+type traceFrame struct {
+	file string
+	line string
+}
+
+// findTraceFrame returns the Error Trace frame in a test file. Newer testify versions print absolute
+// paths and one frame per line, the first frames belong to helpers when they wrap the assertions.
+func (self *failureExtractor) findTraceFrame(details string) *traceFrame {
+	match := regexErrorTrace.FindStringSubmatchIndex(details)
+	if match == nil {
+		return nil
+	}
+
+	frames := []traceFrame{{
+		file: details[match[4]:match[5]],
+		line: details[match[6]:match[7]],
+	}}
+	for _, line := range strings.Split(details[match[1]:], "\n")[1:] {
+		frame := regexTraceFrame.FindStringSubmatch(line)
+		if frame == nil {
+			break
+		}
+		frames = append(frames, traceFrame{
+			file: frame[1],
+			line: frame[2],
+		})
+	}
+
+	for _, frame := range frames {
+		if strings.HasSuffix(frame.file, "_test.go") {
+			return &frame
+		}
+	}
+
+	return &frames[0]
+}
+
+func (self *failureExtractor) findReason(details string) (string, error) {
+	const targetIndex = 0
+	match := regexErrorDtails.FindStringSubmatch(details)
+	if len(match) == 0 {
+		return "", errors.New("No reason matches")
+	}
+	return match[targetIndex], nil
+}
+
+// filterFailedTests drops parent tests which only failed because one of their subtests did,
+// the assertion details live in the output of the subtest.
+func (self *failureExtractor) filterFailedTests(failedTests []testKey) (result []testKey) {
+	for _, key := range failedTests {
+		hasFailedSubtest := false
+		for _, other := range failedTests {
+			if other.pkg == key.pkg && strings.HasPrefix(other.name, key.name+"/") {
+				hasFailedSubtest = true
+				break
+			}
+		}
+
+		if !hasFailedSubtest {
+			result = append(result, key)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_filtering_failed_tests_with_failed_subtests_returns_only_the_subtest_failures(test *testing.T) {
+	extractor := failureExtractor{}
+
+	failedTests := []testKey{
+		{pkg: "elb2c/rest-api-sample/handler", name: "TestGet/not_found"},
+		{pkg: "elb2c/rest-api-sample/handler", name: "TestGet"},
+		{pkg: "elb2c/rest-api-sample/handler", name: "TestGetAll"},
+	}
+
+	result := extractor.filterFailedTests(failedTests)
+
+	assert.Equal(test, 2, len(result))
+	assert.Equal(test, "TestGet/not_found", result[0].name)
+	assert.Equal(test, "TestGetAll", result[1].name)
+}
+
+func Test_building_a_failure_from_a_testify_error_trace_returns_its_file_line_and_reason(test *testing.T) {
+	extractor := failureExtractor{}
+	details := "--- FAIL: TestSave_Create (0.00s)\n" +
+		"    user_repo_test.go:81: \n" +
+		"        \tError Trace:\tuser_repo_test.go:81\n" +
+		"        \tError:      \tNot equal: \n" +
+		"        \t            \texpected: 3\n" +
+		"        \t            \tactual  : 4\n" +
+		"        \tTest:       \tTestSave_Create\n"
+
+	result := extractor.buildFailures(details)
+
+	assert.Equal(test, 1, len(result))
+	assert.Equal(test, "user_repo_test.go", result[0].File)
+	assert.Equal(test, 81, result[0].Line)
+	assert.Contains(test, result[0].Reason, "Not equal:")
+}
+
+func Test_building_a_failure_from_a_t_Errorf_message_returns_its_file_line_and_reason(test *testing.T) {
+	extractor := failureExtractor{}
+	details := "--- FAIL: TestSum (0.00s)\n" +
+		"    sum_test.go:42: got 1, want 2\n"
+
+	result := extractor.buildFailures(details)
+
+	assert.Equal(test, 1, len(result))
+	assert.Equal(test, "sum_test.go", result[0].File)
+	assert.Equal(test, 42, result[0].Line)
+	assert.Equal(test, "got 1, want 2", result[0].Reason)
+	assert.Equal(test, "error", result[0].Category)
+}
+
+func Test_building_a_failure_from_a_multi_line_t_Fatalf_message_returns_the_whole_message(test *testing.T) {
+	extractor := failureExtractor{}
+	details := "=== RUN   TestDecode\n" +
+		"--- FAIL: TestDecode (0.00s)\n" +
+		"    decode_test.go:17: unexpected result\n" +
+		"        got:  {Name:a}\n" +
+		"        want: {Name:b}\n"
+
+	result := extractor.buildFailures(details)
+
+	assert.Equal(test, 1, len(result))
+	assert.Equal(test, "decode_test.go", result[0].File)
+	assert.Equal(test, 17, result[0].Line)
+	assert.Equal(test, "unexpected result\ngot:  {Name:a}\nwant: {Name:b}", result[0].Reason)
+}
+
+func Test_building_a_failure_from_t_Log_messages_followed_by_a_t_Fatalf_message_annotates_the_last_message(test *testing.T) {
+	extractor := failureExtractor{}
+	details := "=== RUN   TestSum\n" +
+		"    sum_test.go:10: setup\n" +
+		"    sum_test.go:12: got 1, want 2\n" +
+		"        more detail\n" +
+		"    sum_test.go:13: second\n" +
+		"--- FAIL: TestSum (0.00s)\n"
+
+	result := extractor.buildFailures(details)
+
+	assert.Equal(test, 1, len(result))
+	assert.Equal(test, 13, result[0].Line)
+	assert.Equal(test, "second\n\nEarlier output of the test:\nsum_test.go:10: setup\nsum_test.go:12: got 1, want 2\nmore detail",
+		result[0].Reason)
+}
+
+func Test_building_a_failure_from_a_repanicked_panic_returns_the_message_without_the_suffix(test *testing.T) {
+	extractor := failureExtractor{workspace: "/github/workspace"}
+	details := "panic: runtime error: invalid memory address or nil pointer dereference [recovered, repanicked]\n" +
+		"[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x718072]\n\n" +
+		"goroutine 20 [running]:\n" +
+		"testing.tRunner.func1.2({0xad1660, 0xb68000})\n" +
+		"\t/usr/local/go/src/testing/testing.go:2123 +0x232\n" +
+		"example.com/app/cart.TestTotal(0x9c20e6a0b48)\n" +
+		"\t/github/workspace/cart/cart_test.go:26 +0x232\n"
+
+	result := extractor.buildFailures(details)
+
+	assert.Equal(test, 1, len(result))
+	assert.Equal(test, 26, result[0].Line)
+	assert.Equal(test, "panic: runtime error: invalid memory address or nil pointer dereference", result[0].Reason)
+	assert.Equal(test, "panic", result[0].Category)
+}
+
+func Test_building_a_failure_from_a_test_timeout_returns_the_timeout_category(test *testing.T) {
+	extractor := failureExtractor{workspace: "/github/workspace"}
+	details := "panic: test timed out after 10m0s\n\n" +
+		"goroutine 7 [running]:\n" +
+		"example.com/app/e2e.TestCheckout(0xc000102000)\n" +
+		"\t/github/workspace/e2e/checkout_test.go:31 +0x5a\n"
+
+	result := extractor.buildFailures(details)
+
+	assert.Equal(test, 1, len(result))
+	assert.Equal(test, "/github/workspace/e2e/checkout_test.go", result[0].File)
+	assert.Equal(test, 31, result[0].Line)
+	assert.Equal(test, "timeout", result[0].Category)
+}
+
+func Test_building_failures_from_a_test_timeout_dump_returns_a_failure_per_running_test(test *testing.T) {
+	extractor := failureExtractor{workspace: "/github/workspace"}
+	details := "panic: test timed out after 10m0s\n" +
+		"\trunning tests:\n" +
+		"\t\tTestCheckout (10m0s)\n" +
+		"\t\tTestCheckout/guest (10m0s)\n" +
+		"\t\tTestPayment (10m0s)\n" +
+		"\n" +
+		"goroutine 17 [running]:\n" +
+		"testing.(*M).startAlarm.func1()\n" +
+		"\t/usr/local/go/src/testing/testing.go:2259 +0x3b9\n" +
+		"created by time.goFunc\n" +
+		"\t/usr/local/go/src/time/sleep.go:176 +0x2d\n" +
+		"\n" +
+		"goroutine 1 [chan receive]:\n" +
+		"testing.(*T).Run(0xc000007860, {0x5c8f3a?, 0x4c6b73?}, 0x5d6f88)\n" +
+		"\t/usr/local/go/src/testing/testing.go:1649 +0x3c9\n" +
+		"main.main()\n" +
+		"\t_testmain.go:49 +0x1c6\n" +
+		"\n" +
+		"goroutine 6 [chan receive]:\n" +
+		"testing.(*T).Run(0xc0000071e0, {0x5c8f3a?, 0x4c6b73?}, 0x5d6f90)\n" +
+		"\t/usr/local/go/src/testing/testing.go:1649 +0x3c9\n" +
+		"example.com/app/e2e.TestCheckout(0xc0000071e0)\n" +
+		"\t/github/workspace/e2e/checkout_test.go:12 +0x25\n" +
+		"\n" +
+		"goroutine 7 [select]:\n" +
+		"example.com/app/e2e.waitForOrder(...)\n" +
+		"\t/github/workspace/e2e/order.go:40\n" +
+		"example.com/app/e2e.TestCheckout.func1(0xc000007a00)\n" +
+		"\t/github/workspace/e2e/checkout_test.go:15 +0x5a\n"
+
+	result := extractor.buildFailures(details)
+
+	// TestCheckout only waits for its subtest
+	assert.Equal(test, 2, len(result))
+	assert.Equal(test, "TestCheckout/guest", result[0].Name)
+	assert.Equal(test, "/github/workspace/e2e/checkout_test.go", result[0].File)
+	assert.Equal(test, 15, result[0].Line)
+	assert.Equal(test, "panic: test timed out after 10m0s", result[0].Reason)
+	assert.Equal(test, "timeout", result[0].Category)
+	assert.Equal(test, "TestPayment", result[1].Name)
+	assert.Equal(test, "", result[1].File)
+	assert.Equal(test, "timeout", result[1].Category)
+}
+
+func Test_building_a_failure_from_details_without_a_file_and_line_returns_a_failure_without_a_location(test *testing.T) {
+	extractor := failureExtractor{}
+
+	result := extractor.buildFailures("=== RUN   TestSum\n--- FAIL: TestSum (0.00s)\n")
+
+	assert.Equal(test, 1, len(result))
+	assert.Equal(test, "", result[0].File)
+	assert.Equal(test, 0, result[0].Line)
+	assert.Equal(test, "The test failed without a message", result[0].Reason)
+	assert.Equal(test, "error", result[0].Category)
+}
+
+func Test_building_failures_from_several_failed_assertions_returns_one_failure_per_assertion(test *testing.T) {
+	extractor := failureExtractor{}
+	details := "--- FAIL: TestSave_Create (0.00s)\n" +
+		"    user_repo_test.go:81: \n" +
+		"        \tError Trace:\tuser_repo_test.go:81\n" +
+		"        \tError:      \tNot equal: \n" +
+		"        \t            \texpected: 3\n" +
+		"        \t            \tactual  : 4\n" +
+		"        \tTest:       \tTestSave_Create\n" +
+		"    user_repo_test.go:82: \n" +
+		"        \tError Trace:\tuser_repo_test.go:82\n" +
+		"        \tError:      \tShould be true\n" +
+		"        \tTest:       \tTestSave_Create\n" +
+		"    user_repo_test.go:85: \n" +
+		"        \tError Trace:\tuser_repo_test.go:85\n" +
+		"        \tError:      \tExpected nil, but got: &errors.errorString{s:\"not found\"}\n" +
+		"        \tTest:       \tTestSave_Create\n"
+
+	result := extractor.buildFailures(details)
+
+	assert.Equal(test, 3, len(result))
+
+	assert.Equal(test, 81, result[0].Line)
+	assert.Contains(test, result[0].Reason, "Not equal:")
+	assert.Contains(test, result[0].Reason, "expected: 3")
+	assert.NotContains(test, result[0].Reason, "Should be true")
+
+	assert.Equal(test, 82, result[1].Line)
+	assert.Contains(test, result[1].Reason, "Should be true")
+	assert.NotContains(test, result[1].Reason, "Expected nil")
+
+	assert.Equal(test, 85, result[2].Line)
+	assert.Contains(test, result[2].Reason, "Expected nil, but got:")
+}
+
+func Test_building_a_failure_from_a_multi_frame_error_trace_returns_the_frame_in_a_test_file(test *testing.T) {
+	extractor := failureExtractor{}
+	details := "        \tError Trace:\t/github/workspace/internal/testutil/assert.go:21\n" +
+		"        \t            \t\t\t\t/github/workspace/handler/user_handler_test.go:53\n" +
+		"        \tError:      \tNot equal: \n" +
+		"        \t            \texpected: 11\n" +
+		"        \t            \tactual  : 1\n"
+
+	result := extractor.buildFailures(details)
+
+	assert.Equal(test, 1, len(result))
+	assert.Equal(test, "/github/workspace/handler/user_handler_test.go", result[0].File)
+	assert.Equal(test, 53, result[0].Line)
+}
+
+func Test_resolving_a_file_name_returns_the_file_in_the_package_directory(test *testing.T) {
+	extractor := failureExtractor{}
+
+	result := extractor.resolvePath("elb2c/rest-api-sample/handler", "user_handler_test.go")
+
+	assert.Equal(test, "handler/user_handler_test.go", result)
+}
+
+func Test_resolving_an_absolute_path_in_the_workspace_returns_the_path_relative_to_the_workspace(test *testing.T) {
+	extractor := failureExtractor{
+		workspace: "/github/workspace",
+	}
+
+	result := extractor.resolvePath("elb2c/rest-api-sample/handler", "/github/workspace/internal/handler/user_handler_test.go")
+
+	assert.Equal(test, "internal/handler/user_handler_test.go", result)
+}
+
+func Test_resolving_an_absolute_path_in_the_runner_checkout_returns_the_path_relative_to_the_repository(test *testing.T) {
+	extractor := failureExtractor{
+		workspace:  "/github/workspace",
+		repository: "rest-api-sample",
+	}
+
+	result := extractor.resolvePath("elb2c/rest-api-sample/handler", "/home/runner/work/rest-api-sample/rest-api-sample/handler/user_handler_test.go")
+
+	assert.Equal(test, "handler/user_handler_test.go", result)
+}
+
+func Test_resolving_a_file_name_of_a_module_package_returns_the_file_in_the_repository_directory(test *testing.T) {
+	extractor := failureExtractor{
+		modules: newModuleResolver("../fixture/workspace"),
+	}
+
+	result := extractor.resolvePath("example.com/svc/internal/store", "store_test.go")
+
+	assert.Equal(test, "internal/store/store_test.go", result)
+}
@@ -1,14 +1,20 @@
 package service
 
 import (
+	"context"
 	"elb2c/gh-action/api/checkrun"
 	"elb2c/gh-action/config"
 	"errors"
+	"fmt"
 	"log"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
 )
 
 type TestFailureAnnotator interface {
-	Annotate() error
+	Annotate(ctx context.Context) error
 }
 
 type TestFailureAnnotateService struct {
@@ -29,42 +35,160 @@ func NewTestFailureAnnotator(cfg *config.Config, parser TestResultParser,
 	}
 }
 
-func (self *TestFailureAnnotateService) Annotate() error {
+// finalizeTimeout how long completing an unfinished check run may take, the context of the
+// annotation may be done already at that time.
+const finalizeTimeout = 30 * time.Second
+
+func (self *TestFailureAnnotateService) Annotate(ctx context.Context) (err error) {
 	if self.config == nil {
 		return errors.New("Config must not be nil")
 	}
 
-	// Create a check run
-	ID, err := self.checkRunCreator.Create()
+	testResults, err := self.config.TestResults()
+	if err != nil {
+		return err
+	}
+
+	rules, err := self.config.AnnotationRules()
 	if err != nil {
 		return err
 	}
 
-	// Parser test results
-	failures, err := self.parser.Parse(self.config.TestResult())
+	// Create a check run
+	checkRun, err := self.checkRunCreator.Create(ctx)
 	if err != nil {
-		log.Printf("Failed to parser the test report because: %s\n", err)
+		return err
 	}
+	ID := checkRun.ID
+
+	// Never leave the check run in progress, it blocks the branch protection
+	completed := false
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("Panic while annotating the test failures: %v", recovered)
+			log.Printf("%s\n%s", err, debug.Stack())
+		}
 
-	// Covert test failures to GitHub annotations
+		if !completed {
+			self.finalize(ctx, ID, err)
+		}
+	}()
+
+	// Parser test results, a missing file or a glob matching nothing is a missing report
+	summary := summaryBuilder{config: self.config}
+	unparsed, missing := 0, 0
+	for _, testResult := range testResults {
+		name := strings.TrimPrefix(testResult, self.config.Workspace)
+		report, err := self.parser.Parse(testResult)
+		if os.IsNotExist(err) {
+			log.Printf("The test report %s is missing\n", testResult)
+			summary.provenances = append(summary.provenances, fmt.Sprintf("- `%s`: not found", name))
+			missing++
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to parser the test report %s because: %s\n", testResult, err)
+			summary.provenances = append(summary.provenances, fmt.Sprintf("- `%s`: failed to parse, %s", name, err))
+			unparsed++
+			continue
+		}
+
+		summary.add(report)
+		summary.provenances = append(summary.provenances, fmt.Sprintf("- `%s`: %d test failure(s)", name, len(report.Failures)))
+	}
+
+	// Covert test failures to GitHub annotations, only the failures at the failure level fail the tests
 	annotations := make([]checkrun.Annotation, 0)
-	for _, failure := range failures {
-		annotation := checkrun.Annotation{
-			Title:     failure.Name,
-			Path:      failure.File,
-			StartLine: failure.Line,
-			EndLine:   failure.Line,
-			Level:     "failure",
-			Message:   failure.Reason,
+	var failing []TestFailure
+	downgraded := 0
+	for _, failure := range summary.failures {
+		level := annotationLevel(rules, failure)
+		if level == levelFailure {
+			failing = append(failing, failure)
+		} else {
+			downgraded++
 		}
 
+		// A failure without a file and line can't be annotated, the summary lists it
+		if failure.File == "" {
+			continue
+		}
+
+		annotation := checkrun.Annotation{
+			Title:      failure.Name,
+			Path:       failure.File,
+			StartLine:  failure.Line,
+			EndLine:    failure.Line,
+			Level:      level,
+			Message:    failure.Reason,
+			RawDetails: failure.Details,
+		}
 		annotations = append(annotations, annotation)
 	}
 
 	// Complete the check run
-	if err := self.checkRunUpdater.Update(ID, annotations); err != nil {
+	verdict := conclude(self.config.Conclusion, failing, summary.packages)
+	headline := verdict.headline(len(summary.failures))
+	if downgraded > 0 {
+		headline = fmt.Sprintf("%s, %d of them annotated as warning or notice", headline, downgraded)
+	}
+	result := checkrun.Result{
+		Conclusion:  verdict.conclusion,
+		Summary:     summary.render(headline),
+		Text:        summary.renderText(),
+		Annotations: annotations,
+	}
+
+	// A report which can't be parsed must not pass as a run without test failures
+	if len(testResults) == missing {
+		result.Conclusion = concludeMissingReport(self.config.Conclusion)
+		result.Summary = fmt.Sprintf("No test result found by TEST_RESULT '%s'", self.config.TestResultFile)
+	} else if unparsed > 0 {
+		result.Conclusion = "failure"
+		result.Summary = summary.render(fmt.Sprintf("%d test failure(s) found, %d of %d test result(s) could not be parsed",
+			len(summary.failures), unparsed, len(testResults)))
+	} else if missing > 0 {
+		if result.Conclusion == "success" {
+			result.Conclusion = concludeMissingReport(self.config.Conclusion)
+		}
+		result.Summary = summary.render(fmt.Sprintf("%s, %d of %d test result(s) not found", headline, missing, len(testResults)))
+	}
+	if result.Conclusion == "action_required" {
+		result.DetailsURL = self.config.RunURL()
+	}
+	if err := self.checkRunUpdater.Update(ctx, ID, result); err != nil {
 		return err
 	}
+	completed = true
+
+	if checkRun.HTMLURL != "" {
+		log.Printf("The test failures are annotated at %s\n", checkRun.HTMLURL)
+	}
 
 	return nil
 }
+
+// finalize completes a check run the annotation failed to complete
+func (self *TestFailureAnnotateService) finalize(ctx context.Context, ID int, cause error) {
+	conclusion := "failure"
+	summary := fmt.Sprintf("Failed to annotate the test failures because: %s", cause)
+	switch ctx.Err() {
+	case context.Canceled:
+		conclusion = "cancelled"
+		summary = "The annotation of the test failures was cancelled"
+	case context.DeadlineExceeded:
+		summary = "The annotation of the test failures timed out"
+	}
+
+	finalizeCtx, cancel := context.WithTimeout(context.Background(), finalizeTimeout)
+	defer cancel()
+
+	result := checkrun.Result{
+		Conclusion:  conclusion,
+		Summary:     summary,
+		Annotations: make([]checkrun.Annotation, 0),
+	}
+	if err := self.checkRunUpdater.Update(finalizeCtx, ID, result); err != nil {
+		log.Printf("Failed to complete the check run %d because: %s\n", ID, err)
+	}
+}
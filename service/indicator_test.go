@@ -1,10 +1,16 @@
 package service
 
 import (
+	"context"
 	"elb2c/gh-action/api/checkrun"
 	"elb2c/gh-action/config"
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -16,6 +22,11 @@ func Test_passing_test_failure_report_returns_no_error_and_creates_and_updates_a
 
 	cfg := config.Config{
 		TestResultFile: "test_report.xml",
+		GitHub: config.GitHub{
+			Repository: "octocat/Hello-World",
+			SHA:        "sha",
+			ServerURL:  "https://github.com",
+		},
 	}
 
 	parserMock := NewMockTestResultParser(mockCtl)
@@ -24,7 +35,7 @@ func Test_passing_test_failure_report_returns_no_error_and_creates_and_updates_a
 	svc := NewTestFailureAnnotator(&cfg, parserMock, creatorMock, updaterMock)
 
 	checkID := 1
-	creatorMock.EXPECT().Create().Return(checkID, nil)
+	creatorMock.EXPECT().Create(gomock.Any()).Return(&checkrun.CheckRun{ID: checkID}, nil)
 
 	var failures []TestFailure
 	failures = append(failures, TestFailure{
@@ -32,14 +43,22 @@ func Test_passing_test_failure_report_returns_no_error_and_creates_and_updates_a
 		File:   "error_test.go",
 		Name:   "Test_passing_an_error_returns_an_error",
 		Reason: "Because of errors",
+		Time:   500 * time.Millisecond,
 	})
 	failures = append(failures, TestFailure{
 		Line:   10,
 		File:   "blender_test.go",
 		Name:   "Test_passing_an_apple_returns_an_apple_juice",
 		Reason: "Because of blender",
+		Time:   10 * time.Millisecond,
 	})
-	parserMock.EXPECT().Parse(gomock.Any()).Return(failures, nil)
+	parserMock.EXPECT().Parse(gomock.Any()).Return(&TestReport{
+		Failures: failures,
+		Packages: []PackageStatistics{
+			{Name: "example.com/error", Passed: 3, Failed: 1, Time: 1200 * time.Millisecond},
+			{Name: "example.com/blender", Passed: 1, Failed: 1, Skipped: 1, Time: 30 * time.Millisecond},
+		},
+	}, nil)
 
 	var annotations []checkrun.Annotation
 	annotations = append(annotations, checkrun.Annotation{
@@ -58,9 +77,73 @@ func Test_passing_test_failure_report_returns_no_error_and_creates_and_updates_a
 		Level:     "failure",
 		Message:   "Because of blender",
 	})
-	updaterMock.EXPECT().Update(checkID, annotations).Return(nil)
+	result := checkrun.Result{
+		Conclusion: "failure",
+		Summary: "2 test failure(s) found\n\n" +
+			"**4** passed, **2** failed, **1** skipped of 7 test(s) in 1.23s\n\n" +
+			"| Package | Passed | Failed | Skipped | Time |\n" +
+			"|:---|---:|---:|---:|---:|\n" +
+			"| `example.com/error` | 3 | 1 | 0 | 1.20s |\n" +
+			"| `example.com/blender` | 1 | 1 | 1 | 0.03s |\n\n" +
+			"<details>\n" +
+			"<summary>2 failing test(s)</summary>\n\n" +
+			"- `Test_passing_an_error_returns_an_error` at " +
+			"[error_test.go:1](https://github.com/octocat/Hello-World/blob/sha/error_test.go#L1) (0.50s)\n" +
+			"- `Test_passing_an_apple_returns_an_apple_juice` at " +
+			"[blender_test.go:10](https://github.com/octocat/Hello-World/blob/sha/blender_test.go#L10) (0.01s)\n\n" +
+			"</details>\n\n" +
+			"- `test_report.xml`: 2 test failure(s)",
+		Annotations: annotations,
+	}
+	updaterMock.EXPECT().Update(gomock.Any(), checkID, result).Return(nil)
 
-	err := svc.Annotate()
+	err := svc.Annotate(context.Background())
+
+	assert.NoError(test, err)
+}
+
+func Test_passing_test_failure_report_returns_no_error_and_sends_the_complete_output_of_the_failures(test *testing.T) {
+	mockCtl := gomock.NewController(test)
+	defer mockCtl.Finish()
+
+	cfg := config.Config{
+		TestResultFile: "test_report.xml",
+		GitHub: config.GitHub{
+			Repository: "octocat/Hello-World",
+			SHA:        "sha",
+			ServerURL:  "https://github.com",
+		},
+	}
+
+	parserMock := NewMockTestResultParser(mockCtl)
+	creatorMock := checkrun.NewMockCreator(mockCtl)
+	updaterMock := checkrun.NewMockUpdater(mockCtl)
+	svc := NewTestFailureAnnotator(&cfg, parserMock, creatorMock, updaterMock)
+
+	checkID := 1
+	creatorMock.EXPECT().Create(gomock.Any()).Return(&checkrun.CheckRun{ID: checkID}, nil)
+
+	details := "user_handler_test.go:53: \nError Trace:\tuser_handler_test.go:53\nError:      \tNot equal: \n" +
+		"            \tDiff:\n            \t-  (float64) 11,\n            \t+  (float64) 1,\nTest:       \tTestList"
+	parserMock.EXPECT().Parse(gomock.Any()).Return(&TestReport{
+		Failures: []TestFailure{{
+			Line:    53,
+			File:    "handler/user_handler_test.go",
+			Name:    "TestList",
+			Reason:  "Error:      \tNot equal: ",
+			Details: details,
+		}},
+	}, nil)
+
+	updaterMock.EXPECT().Update(gomock.Any(), checkID, gomock.Any()).DoAndReturn(func(ctx context.Context, ID int, result checkrun.Result) error {
+		assert.Equal(test, details, result.Annotations[0].RawDetails)
+		assert.Equal(test, "### `TestList`\n\n"+
+			"[handler/user_handler_test.go:53](https://github.com/octocat/Hello-World/blob/sha/handler/user_handler_test.go#L53)\n\n"+
+			"```\n"+details+"\n```", result.Text)
+		return nil
+	})
+
+	err := svc.Annotate(context.Background())
 
 	assert.NoError(test, err)
 }
@@ -79,15 +162,109 @@ func Test_passing_no_test_failure_report_returns_no_error_and_creates_and_update
 	svc := NewTestFailureAnnotator(&cfg, parserMock, creatorMock, updaterMock)
 
 	checkID := 1
-	creatorMock.EXPECT().Create().Return(checkID, nil)
+	creatorMock.EXPECT().Create(gomock.Any()).Return(&checkrun.CheckRun{ID: checkID}, nil)
 
 	var failures []TestFailure
-	parserMock.EXPECT().Parse(gomock.Any()).Return(failures, nil)
+	parserMock.EXPECT().Parse(gomock.Any()).Return(&TestReport{Failures: failures}, nil)
+
+	result := checkrun.Result{
+		Conclusion:  "success",
+		Summary:     "0 test failure(s) found\n\n- `test_report.xml`: 0 test failure(s)",
+		Annotations: make([]checkrun.Annotation, 0),
+	}
+	updaterMock.EXPECT().Update(gomock.Any(), checkID, result).Return(nil)
+
+	err := svc.Annotate(context.Background())
+
+	assert.NoError(test, err)
+}
+
+func Test_passing_failures_matching_an_annotation_level_rule_annotates_them_at_the_level_of_the_rule(test *testing.T) {
+	mockCtl := gomock.NewController(test)
+	defer mockCtl.Finish()
+
+	cfg := config.Config{
+		TestResultFile:       "test_report.xml",
+		AnnotationLevelRules: "warning package=./e2e/...",
+	}
+
+	parserMock := NewMockTestResultParser(mockCtl)
+	creatorMock := checkrun.NewMockCreator(mockCtl)
+	updaterMock := checkrun.NewMockUpdater(mockCtl)
+	svc := NewTestFailureAnnotator(&cfg, parserMock, creatorMock, updaterMock)
+
+	checkID := 1
+	creatorMock.EXPECT().Create(gomock.Any()).Return(&checkrun.CheckRun{ID: checkID}, nil)
+
+	parserMock.EXPECT().Parse(gomock.Any()).Return(&TestReport{Failures: []TestFailure{{
+		Line:   31,
+		File:   "e2e/checkout_test.go",
+		Name:   "TestCheckout",
+		Reason: "Because of the staging environment",
+	}}}, nil)
+
+	updaterMock.EXPECT().Update(gomock.Any(), checkID, gomock.Any()).DoAndReturn(func(ctx context.Context, ID int, result checkrun.Result) error {
+		assert.Equal(test, "warning", result.Annotations[0].Level)
+		assert.Equal(test, "success", result.Conclusion)
+		assert.True(test, strings.HasPrefix(result.Summary, "1 test failure(s) found, 1 of them annotated as warning or notice\n\n"))
+		return nil
+	})
+
+	err := svc.Annotate(context.Background())
+
+	assert.NoError(test, err)
+}
+
+func Test_setting_invalid_annotation_level_rules_returns_an_error(test *testing.T) {
+	mockCtl := gomock.NewController(test)
+	defer mockCtl.Finish()
+
+	cfg := config.Config{
+		TestResultFile:       "test_report.xml",
+		AnnotationLevelRules: "error package=./e2e/...",
+	}
+
+	parserMock := NewMockTestResultParser(mockCtl)
+	creatorMock := checkrun.NewMockCreator(mockCtl)
+	updaterMock := checkrun.NewMockUpdater(mockCtl)
+	svc := NewTestFailureAnnotator(&cfg, parserMock, creatorMock, updaterMock)
 
-	emptyAnnotations := make([]checkrun.Annotation, 0)
-	updaterMock.EXPECT().Update(checkID, emptyAnnotations).Return(nil)
+	err := svc.Annotate(context.Background())
+
+	assert.Error(test, err)
+}
+
+func Test_passing_a_failure_without_a_location_lists_it_in_the_summary_and_concludes_the_check_run_as_failure(test *testing.T) {
+	mockCtl := gomock.NewController(test)
+	defer mockCtl.Finish()
 
-	err := svc.Annotate()
+	cfg := config.Config{
+		TestResultFile: "test_report.xml",
+	}
+
+	parserMock := NewMockTestResultParser(mockCtl)
+	creatorMock := checkrun.NewMockCreator(mockCtl)
+	updaterMock := checkrun.NewMockUpdater(mockCtl)
+	svc := NewTestFailureAnnotator(&cfg, parserMock, creatorMock, updaterMock)
+
+	checkID := 1
+	creatorMock.EXPECT().Create(gomock.Any()).Return(&checkrun.CheckRun{ID: checkID}, nil)
+
+	parserMock.EXPECT().Parse(gomock.Any()).Return(&TestReport{Failures: []TestFailure{{
+		Package: "example.com/app/cart",
+		Name:    "TestAdd",
+		Reason:  "The test failed without a message",
+	}}}, nil)
+
+	updaterMock.EXPECT().Update(gomock.Any(), checkID, gomock.Any()).DoAndReturn(func(ctx context.Context, ID int, result checkrun.Result) error {
+		assert.Empty(test, result.Annotations)
+		assert.Equal(test, "failure", result.Conclusion)
+		assert.True(test, strings.HasPrefix(result.Summary, "1 test failure(s) found\n\n"))
+		assert.Contains(test, result.Summary, "- `TestAdd` in `example.com/app/cart` without a file and line (0.00s)")
+		return nil
+	})
+
+	err := svc.Annotate(context.Background())
 
 	assert.NoError(test, err)
 }
@@ -101,15 +278,15 @@ func Test_missing_test_report_config_returns_an_error(test *testing.T) {
 	updaterMock := checkrun.NewMockUpdater(mockCtl)
 	svc := NewTestFailureAnnotator(nil, parserMock, creatorMock, updaterMock)
 
-	err := svc.Annotate()
+	err := svc.Annotate(context.Background())
 
 	assert.Error(test, err)
-	creatorMock.EXPECT().Create().Times(0)
+	creatorMock.EXPECT().Create(gomock.Any()).Times(0)
 	parserMock.EXPECT().Parse(gomock.Any()).Times(0)
-	updaterMock.EXPECT().Update(gomock.Any(), gomock.Any()).Times(0)
+	updaterMock.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 }
 
-func Test_assuming_failed_to_parse_test_report_returns_no_error(test *testing.T) {
+func Test_assuming_failed_to_parse_test_report_returns_no_error_and_concludes_the_check_run_as_failure(test *testing.T) {
 	mockCtl := gomock.NewController(test)
 	defer mockCtl.Finish()
 
@@ -123,15 +300,19 @@ func Test_assuming_failed_to_parse_test_report_returns_no_error(test *testing.T)
 	svc := NewTestFailureAnnotator(&cfg, parserMock, creatorMock, updaterMock)
 
 	checkID := 1
-	creatorMock.EXPECT().Create().Return(checkID, nil)
+	creatorMock.EXPECT().Create(gomock.Any()).Return(&checkrun.CheckRun{ID: checkID}, nil)
 
 	parseFailed := errors.New("Failed to parse the test report")
 	parserMock.EXPECT().Parse(gomock.Any()).Return(nil, parseFailed)
 
-	emptyAnnotations := make([]checkrun.Annotation, 0)
-	updaterMock.EXPECT().Update(checkID, emptyAnnotations).Return(nil)
+	result := checkrun.Result{
+		Conclusion:  "failure",
+		Summary:     "0 test failure(s) found, 1 of 1 test result(s) could not be parsed\n\n- `test_report.xml`: failed to parse, Failed to parse the test report",
+		Annotations: make([]checkrun.Annotation, 0),
+	}
+	updaterMock.EXPECT().Update(gomock.Any(), checkID, result).Return(nil)
 
-	err := svc.Annotate()
+	err := svc.Annotate(context.Background())
 
 	assert.NoError(test, err)
 }
@@ -150,16 +331,16 @@ func Test_assuming_failed_to_create_a_check_run_returns_an_error(test *testing.T
 	svc := NewTestFailureAnnotator(&cfg, parserMock, creatorMock, updaterMock)
 
 	createFailed := errors.New("Failed to create a check run")
-	creatorMock.EXPECT().Create().Return(0, createFailed)
+	creatorMock.EXPECT().Create(gomock.Any()).Return(nil, createFailed)
 
-	err := svc.Annotate()
+	err := svc.Annotate(context.Background())
 
 	assert.Error(test, err)
 	parserMock.EXPECT().Parse(gomock.Any()).Times(0)
-	updaterMock.EXPECT().Update(gomock.Any(), gomock.Any()).Times(0)
+	updaterMock.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 }
 
-func Test_assuming_failed_to_update_a_check_run_returns_an_error(test *testing.T) {
+func Test_assuming_failed_to_update_a_check_run_returns_an_error_and_completes_the_check_run_as_failure(test *testing.T) {
 	mockCtl := gomock.NewController(test)
 	defer mockCtl.Finish()
 
@@ -173,7 +354,7 @@ func Test_assuming_failed_to_update_a_check_run_returns_an_error(test *testing.T
 	svc := NewTestFailureAnnotator(&cfg, parserMock, creatorMock, updaterMock)
 
 	checkID := 1
-	creatorMock.EXPECT().Create().Return(checkID, nil)
+	creatorMock.EXPECT().Create(gomock.Any()).Return(&checkrun.CheckRun{ID: checkID}, nil)
 
 	var failures []TestFailure
 	failures = append(failures, TestFailure{
@@ -182,12 +363,286 @@ func Test_assuming_failed_to_update_a_check_run_returns_an_error(test *testing.T
 		Name:   "Test_passing_an_error_returns_an_error",
 		Reason: "Because of errors",
 	})
-	parserMock.EXPECT().Parse(gomock.Any()).Return(failures, nil)
+	parserMock.EXPECT().Parse(gomock.Any()).Return(&TestReport{Failures: failures}, nil)
 
 	updateFailed := errors.New("Failed to update a check run")
-	updaterMock.EXPECT().Update(checkID, gomock.Any()).Return(updateFailed)
+	finalResult := checkrun.Result{
+		Conclusion:  "failure",
+		Summary:     "Failed to annotate the test failures because: Failed to update a check run",
+		Annotations: make([]checkrun.Annotation, 0),
+	}
+	gomock.InOrder(
+		updaterMock.EXPECT().Update(gomock.Any(), checkID, gomock.Any()).Return(updateFailed),
+		updaterMock.EXPECT().Update(gomock.Any(), checkID, finalResult).Return(nil),
+	)
+
+	err := svc.Annotate(context.Background())
+
+	assert.Error(test, err)
+}
+
+func Test_passing_multiple_test_reports_returns_no_error_and_merges_the_test_failures_into_one_check_run(test *testing.T) {
+	mockCtl := gomock.NewController(test)
+	defer mockCtl.Finish()
+
+	cfg := config.Config{
+		TestResultFile: "/handler.xml,/repository.xml",
+		GitHub: config.GitHub{
+			Workspace: "/github/workspace",
+		},
+	}
+
+	parserMock := NewMockTestResultParser(mockCtl)
+	creatorMock := checkrun.NewMockCreator(mockCtl)
+	updaterMock := checkrun.NewMockUpdater(mockCtl)
+	svc := NewTestFailureAnnotator(&cfg, parserMock, creatorMock, updaterMock)
+
+	checkID := 1
+	creatorMock.EXPECT().Create(gomock.Any()).Return(&checkrun.CheckRun{ID: checkID}, nil)
+
+	parserMock.EXPECT().Parse("/github/workspace/handler.xml").Return(&TestReport{Failures: []TestFailure{{
+		Line:   1,
+		File:   "error_test.go",
+		Name:   "Test_passing_an_error_returns_an_error",
+		Reason: "Because of errors",
+	}}}, nil)
+	parserMock.EXPECT().Parse("/github/workspace/repository.xml").Return(&TestReport{Failures: []TestFailure{{
+		Line:   10,
+		File:   "blender_test.go",
+		Name:   "Test_passing_an_apple_returns_an_apple_juice",
+		Reason: "Because of blender",
+	}}}, nil)
+
+	updaterMock.EXPECT().Update(gomock.Any(), checkID, gomock.Any()).DoAndReturn(func(ctx context.Context, ID int, result checkrun.Result) error {
+		assert.Equal(test, 2, len(result.Annotations))
+		assert.Equal(test, "error_test.go", result.Annotations[0].Path)
+		assert.Equal(test, "blender_test.go", result.Annotations[1].Path)
+		assert.True(test, strings.HasPrefix(result.Summary, "2 test failure(s) found\n\n"))
+		assert.True(test, strings.HasSuffix(result.Summary, "\n\n- `/handler.xml`: 1 test failure(s)\n- `/repository.xml`: 1 test failure(s)"))
+		return nil
+	})
+
+	err := svc.Annotate(context.Background())
+
+	assert.NoError(test, err)
+}
 
-	err := svc.Annotate()
+func Test_passing_no_test_result_file_returns_no_error_and_concludes_the_check_run_as_failure(test *testing.T) {
+	mockCtl := gomock.NewController(test)
+	defer mockCtl.Finish()
+
+	workspace, _ := ioutil.TempDir("", "workspace")
+	defer os.RemoveAll(workspace)
+
+	cfg := config.Config{
+		TestResultFile: "/",
+		GitHub: config.GitHub{
+			Workspace: workspace,
+		},
+	}
+
+	parserMock := NewMockTestResultParser(mockCtl)
+	creatorMock := checkrun.NewMockCreator(mockCtl)
+	updaterMock := checkrun.NewMockUpdater(mockCtl)
+	svc := NewTestFailureAnnotator(&cfg, parserMock, creatorMock, updaterMock)
+
+	checkID := 1
+	creatorMock.EXPECT().Create(gomock.Any()).Return(&checkrun.CheckRun{ID: checkID}, nil)
+
+	result := checkrun.Result{
+		Conclusion:  "failure",
+		Summary:     "No test result found by TEST_RESULT '/'",
+		Annotations: make([]checkrun.Annotation, 0),
+	}
+	updaterMock.EXPECT().Update(gomock.Any(), checkID, result).Return(nil)
+
+	err := svc.Annotate(context.Background())
+
+	assert.NoError(test, err)
+}
+
+func Test_passing_no_test_result_file_with_the_action_required_policy_concludes_the_check_run_as_action_required(test *testing.T) {
+	mockCtl := gomock.NewController(test)
+	defer mockCtl.Finish()
+
+	workspace, _ := ioutil.TempDir("", "workspace")
+	defer os.RemoveAll(workspace)
+
+	cfg := config.Config{
+		TestResultFile: "/",
+		Conclusion: config.Conclusion{
+			OnMissingReport: "action_required",
+		},
+		GitHub: config.GitHub{
+			Workspace:  workspace,
+			Repository: "octocat/Hello-World",
+			ServerURL:  "https://github.com",
+			RunID:      "42",
+		},
+	}
+
+	parserMock := NewMockTestResultParser(mockCtl)
+	creatorMock := checkrun.NewMockCreator(mockCtl)
+	updaterMock := checkrun.NewMockUpdater(mockCtl)
+	svc := NewTestFailureAnnotator(&cfg, parserMock, creatorMock, updaterMock)
+
+	checkID := 1
+	creatorMock.EXPECT().Create(gomock.Any()).Return(&checkrun.CheckRun{ID: checkID}, nil)
+
+	result := checkrun.Result{
+		Conclusion:  "action_required",
+		DetailsURL:  "https://github.com/octocat/Hello-World/actions/runs/42",
+		Summary:     "No test result found by TEST_RESULT '/'",
+		Annotations: make([]checkrun.Annotation, 0),
+	}
+	updaterMock.EXPECT().Update(gomock.Any(), checkID, result).Return(nil)
+
+	err := svc.Annotate(context.Background())
+
+	assert.NoError(test, err)
+}
+
+func Test_passing_a_nonexistent_test_result_file_concludes_the_check_run_by_the_missing_report_policy(test *testing.T) {
+	mockCtl := gomock.NewController(test)
+	defer mockCtl.Finish()
+
+	for _, testResult := range []string{"/fixture/nonexistent.xml", "/fixture/nonexistent_*.xml"} {
+		cfg := config.Config{
+			TestResultFile: testResult,
+			Conclusion: config.Conclusion{
+				OnMissingReport: "action_required",
+			},
+			GitHub: config.GitHub{
+				Workspace:  "..",
+				Repository: "octocat/Hello-World",
+				ServerURL:  "https://github.com",
+				RunID:      "42",
+			},
+		}
+
+		creatorMock := checkrun.NewMockCreator(mockCtl)
+		updaterMock := checkrun.NewMockUpdater(mockCtl)
+		svc := NewTestFailureAnnotator(&cfg, NewTestResultFormatDetector(&cfg), creatorMock, updaterMock)
+
+		checkID := 1
+		creatorMock.EXPECT().Create(gomock.Any()).Return(&checkrun.CheckRun{ID: checkID}, nil)
+
+		result := checkrun.Result{
+			Conclusion:  "action_required",
+			DetailsURL:  "https://github.com/octocat/Hello-World/actions/runs/42",
+			Summary:     fmt.Sprintf("No test result found by TEST_RESULT '%s'", testResult),
+			Annotations: make([]checkrun.Annotation, 0),
+		}
+		updaterMock.EXPECT().Update(gomock.Any(), checkID, result).Return(nil)
+
+		err := svc.Annotate(context.Background())
+
+		assert.NoError(test, err)
+	}
+}
+
+func Test_passing_a_nonexistent_test_result_file_among_passing_ones_concludes_the_check_run_by_the_missing_report_policy(test *testing.T) {
+	mockCtl := gomock.NewController(test)
+	defer mockCtl.Finish()
+
+	cfg := config.Config{
+		TestResultFile: "/handler.xml,/repository.xml",
+		Conclusion: config.Conclusion{
+			OnMissingReport: "neutral",
+		},
+	}
+
+	parserMock := NewMockTestResultParser(mockCtl)
+	creatorMock := checkrun.NewMockCreator(mockCtl)
+	updaterMock := checkrun.NewMockUpdater(mockCtl)
+	svc := NewTestFailureAnnotator(&cfg, parserMock, creatorMock, updaterMock)
+
+	checkID := 1
+	creatorMock.EXPECT().Create(gomock.Any()).Return(&checkrun.CheckRun{ID: checkID}, nil)
+
+	parserMock.EXPECT().Parse("/handler.xml").Return(&TestReport{}, nil)
+	parserMock.EXPECT().Parse("/repository.xml").Return(nil, &os.PathError{Op: "open", Path: "/repository.xml", Err: os.ErrNotExist})
+
+	updaterMock.EXPECT().Update(gomock.Any(), checkID, gomock.Any()).DoAndReturn(func(ctx context.Context, ID int, result checkrun.Result) error {
+		assert.Equal(test, "neutral", result.Conclusion)
+		assert.Equal(test, "0 test failure(s) found, 1 of 2 test result(s) not found\n\n"+
+			"- `/handler.xml`: 0 test failure(s)\n- `/repository.xml`: not found", result.Summary)
+		return nil
+	})
+
+	err := svc.Annotate(context.Background())
+
+	assert.NoError(test, err)
+}
+
+func Test_assuming_the_parser_panicking_returns_an_error_and_completes_the_check_run_as_failure(test *testing.T) {
+	mockCtl := gomock.NewController(test)
+	defer mockCtl.Finish()
+
+	cfg := config.Config{
+		TestResultFile: "test_report.xml",
+	}
+
+	parserMock := NewMockTestResultParser(mockCtl)
+	creatorMock := checkrun.NewMockCreator(mockCtl)
+	updaterMock := checkrun.NewMockUpdater(mockCtl)
+	svc := NewTestFailureAnnotator(&cfg, parserMock, creatorMock, updaterMock)
+
+	checkID := 1
+	creatorMock.EXPECT().Create(gomock.Any()).Return(&checkrun.CheckRun{ID: checkID}, nil)
+
+	parserMock.EXPECT().Parse(gomock.Any()).DoAndReturn(func(testResult string) (*TestReport, error) {
+		panic("index out of range")
+	})
+
+	result := checkrun.Result{
+		Conclusion:  "failure",
+		Summary:     "Failed to annotate the test failures because: Panic while annotating the test failures: index out of range",
+		Annotations: make([]checkrun.Annotation, 0),
+	}
+	updaterMock.EXPECT().Update(gomock.Any(), checkID, result).Return(nil)
+
+	err := svc.Annotate(context.Background())
 
 	assert.Error(test, err)
+	assert.Equal(test, "Panic while annotating the test failures: index out of range", err.Error())
+}
+
+func Test_assuming_the_annotation_being_cancelled_returns_an_error_and_completes_the_check_run_as_cancelled(test *testing.T) {
+	mockCtl := gomock.NewController(test)
+	defer mockCtl.Finish()
+
+	cfg := config.Config{
+		TestResultFile: "test_report.xml",
+	}
+
+	parserMock := NewMockTestResultParser(mockCtl)
+	creatorMock := checkrun.NewMockCreator(mockCtl)
+	updaterMock := checkrun.NewMockUpdater(mockCtl)
+	svc := NewTestFailureAnnotator(&cfg, parserMock, creatorMock, updaterMock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	checkID := 1
+	creatorMock.EXPECT().Create(gomock.Any()).Return(&checkrun.CheckRun{ID: checkID}, nil)
+
+	// SIGTERM arrives while parsing
+	parserMock.EXPECT().Parse(gomock.Any()).DoAndReturn(func(testResult string) (*TestReport, error) {
+		cancel()
+		return &TestReport{}, nil
+	})
+
+	result := checkrun.Result{
+		Conclusion:  "cancelled",
+		Summary:     "The annotation of the test failures was cancelled",
+		Annotations: make([]checkrun.Annotation, 0),
+	}
+	gomock.InOrder(
+		updaterMock.EXPECT().Update(ctx, checkID, gomock.Any()).Return(context.Canceled),
+		updaterMock.EXPECT().Update(gomock.Not(ctx), checkID, result).Return(nil),
+	)
+
+	err := svc.Annotate(ctx)
+
+	assert.Equal(test, context.Canceled, err)
 }
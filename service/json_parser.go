@@ -0,0 +1,208 @@
+package service
+
+import (
+	"bufio"
+	"elb2c/gh-action/config"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// testEvent an event emitted by `go test -json` (see `go doc test2json`)
+type testEvent struct {
+	Action      string  `json:"Action"`
+	Package     string  `json:"Package"`
+	Test        string  `json:"Test"`
+	Elapsed     float64 `json:"Elapsed"`
+	Output      string  `json:"Output"`
+	ImportPath  string  `json:"ImportPath"`
+	FailedBuild string  `json:"FailedBuild"`
+}
+
+type TestResultJSONParseService struct {
+	extractor failureExtractor
+}
+
+func NewTestResultJSONParser(cfg *config.Config) TestResultParser {
+	return &TestResultJSONParseService{
+		extractor: newFailureExtractor(cfg),
+	}
+}
+
+func (self *TestResultJSONParseService) Parse(testResult string) (*TestReport, error) {
+	if testResult == "" {
+		return nil, errors.New("TestResult must not be empty")
+	}
+
+	jsonFile, err := os.Open(testResult)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Successful open the test result file: %s\n", testResult)
+	defer jsonFile.Close()
+
+	// Collect the output of every test in the order it was printed
+	outputs := make(map[testKey]*strings.Builder)
+	var startedTests []testKey
+	var failedTests []testKey
+	var failedPackages []string
+	// The build output is keyed by the import path of the build, e.g. `pkg [pkg.test]`
+	buildOutputs := make(map[string]*strings.Builder)
+	failedBuilds := make(map[string]string)
+	currentBuild := ""
+	finished := make(map[testKey]bool)
+	elapsed := make(map[testKey]float64)
+	var statistics statisticsCollector
+	events := 0
+	scanner := bufio.NewScanner(jsonFile)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var event testEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			// `go test -json` of Go 1.23 and earlier passes through build failures as plain text
+			if strings.HasPrefix(line, "# ") {
+				currentBuild = strings.TrimPrefix(line, "# ")
+			}
+			if currentBuild != "" {
+				appendOutput(buildOutputs, currentBuild, line+"\n")
+			}
+			continue
+		}
+		events++
+
+		if event.Action == "build-output" {
+			appendOutput(buildOutputs, event.ImportPath, event.Output)
+			continue
+		}
+
+		key := testKey{pkg: event.Package, name: event.Test}
+		if event.Action == "output" {
+			if outputs[key] == nil {
+				outputs[key] = &strings.Builder{}
+				if event.Test != "" {
+					startedTests = append(startedTests, key)
+				}
+			}
+			outputs[key].WriteString(event.Output)
+			continue
+		}
+
+		// The events of a package without a test tell how long the package took
+		if event.Test == "" {
+			if event.Action == resultPass || event.Action == resultFail {
+				statistics.get(event.Package).Time = seconds(event.Elapsed)
+			}
+			if event.Action == resultFail && !hasFailedTest(failedTests, event.Package) {
+				failedPackages = append(failedPackages, event.Package)
+				failedBuilds[event.Package] = event.FailedBuild
+			}
+			continue
+		}
+
+		switch event.Action {
+		case resultFail:
+			failedTests = append(failedTests, key)
+			finished[key] = true
+			elapsed[key] = event.Elapsed
+			statistics.record(event.Package, event.Test, event.Action)
+		case resultPass, resultSkip:
+			finished[key] = true
+			statistics.record(event.Package, event.Test, event.Action)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := verifyNotEmpty(testResult, events > 0, "test event"); err != nil {
+		return nil, err
+	}
+
+	report := &TestReport{}
+	for _, key := range self.extractor.filterFailedTests(failedTests) {
+		var details string
+		if output := outputs[key]; output != nil {
+			details = output.String()
+		}
+
+		testFailures := self.extractor.buildFailures(details)
+		for _, failure := range testFailures {
+			failure.Name = key.name
+			failure.Package = key.pkg
+			failure.File = self.extractor.resolvePath(key.pkg, failure.File)
+			failure.Details = details
+			failure.Time = seconds(elapsed[key])
+			report.Failures = append(report.Failures, failure)
+		}
+	}
+
+	for _, pkg := range failedPackages {
+		var details strings.Builder
+		details.WriteString(findBuildOutput(buildOutputs, pkg, failedBuilds[pkg]))
+		for _, key := range startedTests {
+			if key.pkg == pkg && !finished[key] {
+				details.WriteString(outputs[key].String())
+			}
+		}
+		if output := outputs[testKey{pkg: pkg}]; output != nil {
+			details.WriteString(output.String())
+		}
+
+		statistics.record(pkg, "", resultFail)
+		report.Failures = append(report.Failures, self.extractor.buildPackageFailures(pkg, details.String())...)
+	}
+	report.Packages = statistics.collect()
+
+	return report, nil
+}
+
+func appendOutput(outputs map[string]*strings.Builder, key string, output string) {
+	if outputs[key] == nil {
+		outputs[key] = &strings.Builder{}
+	}
+	outputs[key].WriteString(output)
+}
+
+// findBuildOutput returns the output of the build a package failed by. Without the failed build in the
+// report the builds of the package itself are looked up.
+func findBuildOutput(buildOutputs map[string]*strings.Builder, pkg string, failedBuild string) string {
+	if failedBuild != "" {
+		if output := buildOutputs[failedBuild]; output != nil {
+			return output.String()
+		}
+		return ""
+	}
+
+	var importPaths []string
+	for importPath := range buildOutputs {
+		if strings.SplitN(importPath, " ", 2)[0] == pkg {
+			importPaths = append(importPaths, importPath)
+		}
+	}
+	sort.Strings(importPaths)
+
+	var details strings.Builder
+	for _, importPath := range importPaths {
+		details.WriteString(buildOutputs[importPath].String())
+	}
+
+	return details.String()
+}
+
+func hasFailedTest(failedTests []testKey, pkg string) bool {
+	for _, key := range failedTests {
+		if key.pkg == pkg {
+			return true
+		}
+	}
+
+	return false
+}
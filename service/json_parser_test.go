@@ -0,0 +1,169 @@
+package service
+
+import (
+	"elb2c/gh-action/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_passing_a_go_test_json_report_including_test_failures_returns_test_failure_details(test *testing.T) {
+	svc := TestResultJSONParseService{}
+
+	result, err := svc.Parse("../fixture/test_report_gotest_json_f.json")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 2, len(result.Failures))
+
+	assert.Equal(test, "TestList", result.Failures[0].Name)
+	assert.Equal(test, "handler/user_handler_test.go", result.Failures[0].File)
+	assert.Equal(test, 53, result.Failures[0].Line)
+	assert.Contains(test, result.Failures[0].Reason, "Not equal:")
+
+	assert.Equal(test, "TestSave_Create", result.Failures[1].Name)
+	assert.Equal(test, "repository/user_repo_test.go", result.Failures[1].File)
+	assert.Equal(test, 81, result.Failures[1].Line)
+	assert.Contains(test, result.Failures[1].Reason, "Not equal:")
+}
+
+func Test_passing_a_go_test_json_report_without_test_failures_returns_a_zero_test_failure_array(test *testing.T) {
+	svc := TestResultJSONParseService{}
+
+	result, err := svc.Parse("../fixture/test_report_gotest_json_s.json")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 0, len(result.Failures))
+}
+
+func Test_passing_an_empty_go_test_json_report_path_returns_an_error(test *testing.T) {
+	svc := TestResultJSONParseService{}
+
+	_, err := svc.Parse("")
+
+	assert.Error(test, err)
+	assert.Equal(test, "TestResult must not be empty", err.Error())
+}
+
+func Test_passing_gotest_json_failure_report_returns_the_statistics_of_the_packages(test *testing.T) {
+	svc := TestResultJSONParseService{}
+
+	result, err := svc.Parse("../fixture/test_report_gotest_json_f.json")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 2, len(result.Packages))
+	assert.Equal(test, "elb2c/rest-api-sample/handler", result.Packages[0].Name)
+	assert.Equal(test, 17, result.Packages[0].Passed)
+	assert.Equal(test, 1, result.Packages[0].Failed)
+	assert.Equal(test, 18*time.Millisecond, result.Packages[0].Time)
+	assert.Equal(test, "elb2c/rest-api-sample/repository", result.Packages[1].Name)
+	assert.Equal(test, 5, result.Packages[1].Passed)
+	assert.Equal(test, 1, result.Packages[1].Failed)
+}
+
+func Test_passing_a_go_test_json_report_including_a_failure_without_a_location_returns_every_failure(test *testing.T) {
+	svc := TestResultJSONParseService{}
+
+	result, err := svc.Parse("../fixture/test_report_gotest_json_failnow.json")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 2, len(result.Failures))
+
+	assert.Equal(test, "TestAdd", result.Failures[0].Name)
+	assert.Equal(test, "", result.Failures[0].File)
+	assert.Equal(test, 0, result.Failures[0].Line)
+
+	assert.Equal(test, "TestRemove", result.Failures[1].Name)
+	assert.Equal(test, "cart/cart_test.go", result.Failures[1].File)
+	assert.Equal(test, 21, result.Failures[1].Line)
+	assert.Equal(test, "got 1, want 2", result.Failures[1].Reason)
+}
+
+func Test_passing_a_go_test_json_report_including_packages_failing_without_a_failing_test_returns_package_failures(test *testing.T) {
+	svc := NewTestResultJSONParser(&config.Config{
+		GitHub: config.GitHub{
+			Workspace: "/github/workspace",
+		},
+	})
+
+	result, err := svc.Parse("../fixture/test_report_gotest_json_package_f.json")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 2, len(result.Failures))
+
+	assert.Equal(test, "example.com/app/cart", result.Failures[0].Name)
+	assert.Equal(test, "example.com/app/cart", result.Failures[0].Package)
+	assert.Equal(test, "", result.Failures[0].File)
+	assert.Equal(test, "FAIL\texample.com/app/cart [build failed]", result.Failures[0].Reason)
+
+	assert.Equal(test, "TestSlow", result.Failures[1].Name)
+	assert.Equal(test, "example.com/app/slow", result.Failures[1].Package)
+	assert.Equal(test, "slow/slow_test.go", result.Failures[1].File)
+	assert.Equal(test, 9, result.Failures[1].Line)
+	assert.Equal(test, "panic: test timed out after 1s", result.Failures[1].Reason)
+	assert.Equal(test, "timeout", result.Failures[1].Category)
+
+	assert.Equal(test, []PackageStatistics{
+		{Name: "example.com/app/cart", Failed: 1},
+		{Name: "example.com/app/slow", Passed: 1, Failed: 1, Time: seconds(1.007)},
+	}, result.Packages)
+}
+
+func Test_passing_a_go_test_json_report_including_a_failing_subtest_counts_the_subtests_only(test *testing.T) {
+	svc := TestResultJSONParseService{}
+
+	result, err := svc.Parse("../fixture/test_report_gotest_json_subtest_f.json")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 1, len(result.Failures))
+	assert.Equal(test, "TestSum/negative", result.Failures[0].Name)
+	assert.Equal(test, []PackageStatistics{
+		{Name: "example.com/app/cart", Passed: 2, Failed: 1, Time: seconds(0.003)},
+	}, result.Packages)
+}
+
+func Test_passing_a_go_test_json_report_of_a_timed_out_subtest_returns_the_subtest_failure(test *testing.T) {
+	svc := NewTestResultJSONParser(&config.Config{
+		GitHub: config.GitHub{
+			Workspace: "/github/workspace",
+		},
+	})
+
+	result, err := svc.Parse("../fixture/test_report_gotest_json_timeout.json")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 1, len(result.Failures))
+	assert.Equal(test, "TestSlow/inner", result.Failures[0].Name)
+	assert.Equal(test, "example.com/app/slow", result.Failures[0].Package)
+	assert.Equal(test, "slow/slow_test.go", result.Failures[0].File)
+	assert.Equal(test, 12, result.Failures[0].Line)
+	assert.Equal(test, "panic: test timed out after 1s", result.Failures[0].Reason)
+	assert.Equal(test, "timeout", result.Failures[0].Category)
+}
+
+func Test_passing_a_go_test_json_report_including_build_output_events_returns_the_compiler_errors(test *testing.T) {
+	svc := TestResultJSONParseService{}
+
+	result, err := svc.Parse("../fixture/test_report_gotest_json_build_output.json")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 2, len(result.Failures))
+	assert.Equal(test, "TestSum", result.Failures[0].Name)
+	assert.Equal(test, "example.com/app/broken", result.Failures[1].Name)
+	assert.Equal(test, "example.com/app/broken", result.Failures[1].Package)
+	assert.Equal(test, "broken/broken_test.go", result.Failures[1].File)
+	assert.Equal(test, 6, result.Failures[1].Line)
+	assert.Equal(test, "undefined: undefinedName", result.Failures[1].Reason)
+}
+
+func Test_passing_a_go_test_json_report_including_plain_build_output_returns_the_compiler_errors(test *testing.T) {
+	svc := TestResultJSONParseService{}
+
+	result, err := svc.Parse("../fixture/test_report_gotest_json_build_text.json")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 2, len(result.Failures))
+	assert.Equal(test, "broken/broken_test.go", result.Failures[1].File)
+	assert.Equal(test, 6, result.Failures[1].Line)
+	assert.Equal(test, "undefined: undefinedName", result.Failures[1].Reason)
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"io/ioutil"
+	"log"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var regexModulePath = regexp.MustCompile(`(?m)^\s*module\s+("[^"]+"|\S+)`)
+
+// goModule a Go module in the repository, dir is relative to the root of the repository
+type goModule struct {
+	path string
+	dir  string
+}
+
+// moduleResolver resolves package import paths to directories of the repository by the go.mod files,
+// and the go.work file of a multi-module repository, in the workspace.
+type moduleResolver struct {
+	modules []goModule
+}
+
+func newModuleResolver(workspace string) moduleResolver {
+	if workspace == "" {
+		return moduleResolver{}
+	}
+
+	dirs := []string{"."}
+	if content, err := ioutil.ReadFile(filepath.Join(workspace, "go.work")); err == nil {
+		dirs = append(dirs, parseGoWorkUses(string(content))...)
+	}
+
+	var modules []goModule
+	for _, dir := range dirs {
+		content, err := ioutil.ReadFile(filepath.Join(workspace, dir, "go.mod"))
+		if err != nil {
+			continue
+		}
+
+		match := regexModulePath.FindStringSubmatch(string(content))
+		if match == nil {
+			log.Printf("No module path found in %s\n", filepath.Join(dir, "go.mod"))
+			continue
+		}
+
+		modulePath := match[1]
+		if unquoted, err := strconv.Unquote(modulePath); err == nil {
+			modulePath = unquoted
+		}
+		modules = append(modules, goModule{
+			path: modulePath,
+			dir:  path.Clean(filepath.ToSlash(dir)),
+		})
+	}
+
+	return moduleResolver{
+		modules: modules,
+	}
+}
+
+// resolve returns the directory of a package relative to the root of the repository. The module with
+// the longest matching path wins, so the packages of nested modules resolve to their own directories.
+func (self *moduleResolver) resolve(importPath string) (string, bool) {
+	var matched *goModule
+	for i, module := range self.modules {
+		if importPath != module.path && !strings.HasPrefix(importPath, module.path+"/") {
+			continue
+		}
+
+		if matched == nil || len(module.path) > len(matched.path) {
+			matched = &self.modules[i]
+		}
+	}
+
+	if matched == nil {
+		return "", false
+	}
+
+	return path.Join(matched.dir, strings.TrimPrefix(importPath, matched.path)), true
+}
+
+// parseGoWorkUses returns the module directories of the `use` directives in a go.work file
+func parseGoWorkUses(content string) []string {
+	var dirs []string
+	inBlock := false
+	for _, line := range strings.Split(content, "\n") {
+		if index := strings.Index(line, "//"); index >= 0 {
+			line = line[:index]
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock && line != "":
+			dirs = append(dirs, unquoteGoWorkPath(line))
+		case strings.HasPrefix(line, "use") && strings.TrimSpace(strings.TrimPrefix(line, "use")) == "(":
+			inBlock = true
+		case strings.HasPrefix(line, "use "):
+			dirs = append(dirs, unquoteGoWorkPath(strings.TrimSpace(strings.TrimPrefix(line, "use "))))
+		}
+	}
+
+	return dirs
+}
+
+func unquoteGoWorkPath(dir string) string {
+	if unquoted, err := strconv.Unquote(dir); err == nil {
+		return unquoted
+	}
+
+	return dir
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_resolving_a_package_of_the_root_module_returns_its_directory(test *testing.T) {
+	resolver := newModuleResolver("../fixture/workspace")
+
+	result, ok := resolver.resolve("example.com/svc/internal/store")
+
+	assert.True(test, ok)
+	assert.Equal(test, "internal/store", result)
+}
+
+func Test_resolving_the_root_package_of_the_root_module_returns_the_root_directory(test *testing.T) {
+	resolver := newModuleResolver("../fixture/workspace")
+
+	result, ok := resolver.resolve("example.com/svc")
+
+	assert.True(test, ok)
+	assert.Equal(test, ".", result)
+}
+
+func Test_resolving_a_package_of_a_nested_module_in_go_work_returns_its_directory(test *testing.T) {
+	resolver := newModuleResolver("../fixture/workspace")
+
+	result, ok := resolver.resolve("example.com/svc/tools/lint")
+
+	assert.True(test, ok)
+	assert.Equal(test, "tools/lint", result)
+}
+
+func Test_resolving_a_package_of_a_quoted_module_in_go_work_returns_its_directory(test *testing.T) {
+	resolver := newModuleResolver("../fixture/workspace")
+
+	result, ok := resolver.resolve("example.com/legacy/handler")
+
+	assert.True(test, ok)
+	assert.Equal(test, "legacy/handler", result)
+}
+
+func Test_resolving_a_package_of_an_unknown_module_returns_not_ok(test *testing.T) {
+	resolver := newModuleResolver("../fixture/workspace")
+
+	_, ok := resolver.resolve("example.com/svcs/handler")
+
+	assert.False(test, ok)
+}
+
+func Test_resolving_a_package_without_a_workspace_returns_not_ok(test *testing.T) {
+	resolver := newModuleResolver("")
+
+	_, ok := resolver.resolve("example.com/svc/internal/store")
+
+	assert.False(test, ok)
+}
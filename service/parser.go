@@ -1,27 +1,41 @@
 package service
 
 import (
+	"bytes"
+	"elb2c/gh-action/config"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
-	"regexp"
-	"strconv"
 	"strings"
+	"time"
 )
 
 //go:generate mockgen -package=service -self_package=elb2c/gh-action/service -destination=mock_parser.go elb2c/gh-action/service TestResultParser
 
 type TestResultParser interface {
-	Parse(testResult string) ([]TestFailure, error)
+	Parse(testResult string) (*TestReport, error)
 }
 
+// TestReport the test failures and the statistics of the packages in a test result
+type TestReport struct {
+	Failures []TestFailure
+	Packages []PackageStatistics
+}
+
+// TestFailure a failed assertion of a test, the details are the complete output of the test and the
+// category tells how it failed.
 type TestFailure struct {
-	Line   int
-	File   string
-	Name   string
-	Reason string
+	Line     int
+	File     string
+	Package  string
+	Name     string
+	Reason   string
+	Details  string
+	Category string
+	Time     time.Duration
 }
 
 type testSuites struct {
@@ -39,26 +53,25 @@ type testSuite struct {
 }
 
 type testCase struct {
-	XMLName   xml.Name `xml:"testcase"`
-	ClassName string   `xml:"classname,attr"`
-	Name      string   `xml:"name,attr"`
-	Time      float64  `xml:"time,attr"`
-	Details   string   `xml:"failure"`
+	XMLName   xml.Name  `xml:"testcase"`
+	ClassName string    `xml:"classname,attr"`
+	Name      string    `xml:"name,attr"`
+	Time      float64   `xml:"time,attr"`
+	Details   string    `xml:"failure"`
+	Skipped   *struct{} `xml:"skipped"`
 }
 
 type TestResultParseService struct {
+	extractor failureExtractor
 }
 
-var (
-	regexErrorTrace  = regexp.MustCompile(`Error Trace:(\s+)(\w+\.\w+)\:(\d+)`)
-	regexErrorDtails = regexp.MustCompile(`Error:(\s+)(.*)(\s.*)(\s.*)`)
-)
-
-func NewTestResultParser() TestResultParser {
-	return &TestResultParseService{}
+func NewTestResultParser(cfg *config.Config) TestResultParser {
+	return &TestResultParseService{
+		extractor: newFailureExtractor(cfg),
+	}
 }
 
-func (self *TestResultParseService) Parse(testResult string) ([]TestFailure, error) {
+func (self *TestResultParseService) Parse(testResult string) (*TestReport, error) {
 	if testResult == "" {
 		return nil, errors.New("TestResult must not be empty")
 	}
@@ -75,89 +88,122 @@ func (self *TestResultParseService) Parse(testResult string) ([]TestFailure, err
 		return nil, err
 	}
 
-	var testsuites testSuites
-	xml.Unmarshal(byteValue, &testsuites)
+	testsuites, err := self.decode(testResult, byteValue)
+	if err != nil {
+		return nil, err
+	}
 
-	var failures []TestFailure
+	report := &TestReport{
+		Packages: self.collectStatistics(testsuites.TestSuites),
+	}
 	failedTestCases := self.filterFailedTestCases(testsuites.TestSuites)
 	for _, testCase := range failedTestCases {
-		failure, err := self.buildFailure(testCase.Details)
-		if err != nil {
-			return nil, err
+		caseFailures := self.extractor.buildFailures(testCase.Details)
+		for _, failure := range caseFailures {
+			failure.Name = testCase.Name
+			failure.Package = testCase.ClassName
+			failure.File = self.extractor.resolvePath(testCase.ClassName, failure.File)
+			failure.Details = testCase.Details
+			failure.Time = seconds(testCase.Time)
+			report.Failures = append(report.Failures, failure)
 		}
-		failure.Name = testCase.Name
-		failure.File = self.getDirectory(testCase.ClassName) + "/" + failure.File
-		failures = append(failures, *failure)
 	}
 
-	return failures, nil
+	return report, nil
 }
 
-func (self *TestResultParseService) filterFailedTestCases(testsuites []testSuite) (result []testCase) {
-	for i := 0; i < len(testsuites); i++ {
-		if testsuites[i].Failures > 0 {
-			for j := 0; j < len(testsuites[i].TestCases); j++ {
-				if len(testsuites[i].TestCases[j].Details) > 0 {
-					result = append(result, testsuites[i].TestCases[j])
-				}
+// collectStatistics counts the test cases of every test suite, the time of a suite is the sum of its
+// test cases when the report has none.
+func (self *TestResultParseService) collectStatistics(testsuites []testSuite) []PackageStatistics {
+	var statistics statisticsCollector
+	for _, testsuite := range testsuites {
+		var caseTime float64
+		for _, testCase := range testsuite.TestCases {
+			caseTime += testCase.Time
+			switch {
+			case len(testCase.Details) > 0:
+				statistics.record(testsuite.Name, testCase.Name, resultFail)
+			case testCase.Skipped != nil:
+				statistics.record(testsuite.Name, testCase.Name, resultSkip)
+			default:
+				statistics.record(testsuite.Name, testCase.Name, resultPass)
 			}
 		}
+
+		if testsuite.Time > 0 {
+			statistics.get(testsuite.Name).Time = seconds(testsuite.Time)
+		} else {
+			statistics.get(testsuite.Name).Time = seconds(caseTime)
+		}
 	}
 
-	return
+	return statistics.collect()
 }
 
-func (self *TestResultParseService) buildFailure(details string) (*TestFailure, error) {
-	lineNumber, err := self.findLineNumber(details)
-	if err != nil {
-		return nil, err
-	}
-
-	fileName, err := self.findFileName(details)
-	if err != nil {
-		return nil, err
-	}
+// decode decodes a JUnit report, the root element is either <testsuites> or a single <testsuite>
+func (self *TestResultParseService) decode(testResult string, byteValue []byte) (*testSuites, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(byteValue))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode the test result %s at byte offset %d because: %s",
+				testResult, decoder.InputOffset(), err)
+		}
 
-	reason, err := self.findReason(details)
-	if err != nil {
-		return nil, err
-	}
+		root, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
 
-	return &TestFailure{
-		Line:   lineNumber,
-		File:   fileName,
-		Reason: reason,
-	}, nil
-}
+		var testsuites testSuites
+		switch root.Name.Local {
+		case "testsuites":
+			err = decoder.DecodeElement(&testsuites, &root)
+		case "testsuite":
+			var testsuite testSuite
+			err = decoder.DecodeElement(&testsuite, &root)
+			testsuites.TestSuites = append(testsuites.TestSuites, testsuite)
+		default:
+			return nil, fmt.Errorf("Invalid test result %s. the root element should be <testsuites> or <testsuite> instead of <%s>",
+				testResult, root.Name.Local)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode the test result %s at byte offset %d because: %s",
+				testResult, decoder.InputOffset(), err)
+		}
 
-func (self *TestResultParseService) getDirectory(className string) string {
-	array := strings.Split(className, "/")
-	return array[len(array)-1]
-}
+		if err := verifyNotEmpty(testResult, len(testsuites.TestSuites) > 0, "test suite"); err != nil {
+			return nil, err
+		}
 
-func (self *TestResultParseService) findFileName(details string) (string, error) {
-	const targetIndex = 2
-	match := regexErrorTrace.FindStringSubmatch(details)
-	if len(match) == 0 {
-		return "", errors.New("No file name matches")
+		return &testsuites, nil
 	}
-	return match[targetIndex], nil
 }
 
-func (self *TestResultParseService) findLineNumber(details string) (int, error) {
-	const targetIndex = 3
-	match := regexErrorTrace.FindStringSubmatch(details)
-	if len(match) == 0 {
-		return 0, errors.New("No line number matches")
+// verifyNotEmpty an empty report is more likely a broken test run than a run without tests
+func verifyNotEmpty(testResult string, found bool, what string) error {
+	if found {
+		return nil
 	}
-	return strconv.Atoi(match[targetIndex])
+
+	return fmt.Errorf("Invalid test result %s. no %s found", testResult, what)
 }
 
-func (self *TestResultParseService) findReason(details string) (string, error) {
-	const targetIndex = 0
-	match := regexErrorDtails.FindStringSubmatch(details)
-	if len(match) == 0 {
-		return "", errors.New("No reason matches")
+func (self *TestResultParseService) filterFailedTestCases(testsuites []testSuite) (result []testCase) {
+	for i := 0; i < len(testsuites); i++ {
+		if testsuites[i].Failures > 0 {
+			for j := 0; j < len(testsuites[i].TestCases); j++ {
+				if len(testsuites[i].TestCases[j].Details) > 0 {
+					testCase := testsuites[i].TestCases[j]
+					// go-junit-report keeps only the last element of the import path in the class name
+					if strings.HasSuffix(testsuites[i].Name, "/"+testCase.ClassName) {
+						testCase.ClassName = testsuites[i].Name
+					}
+					result = append(result, testCase)
+				}
+			}
+		}
 	}
-	return match[targetIndex], nil
+
+	return
 }
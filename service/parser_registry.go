@@ -0,0 +1,165 @@
+package service
+
+import (
+	"bytes"
+	"elb2c/gh-action/config"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ParserFactory creates a test result parser
+type ParserFactory func(cfg *config.Config) TestResultParser
+
+// FormatSniffer reports whether the first bytes of a test result are in the format of a parser
+type FormatSniffer func(head []byte) bool
+
+type parserRegistration struct {
+	name    string
+	sniff   FormatSniffer
+	factory ParserFactory
+}
+
+const sniffLength = 4096
+
+var (
+	parserRegistry []parserRegistration
+
+	// `go test -json` passes through build failures as plain text before the first event
+	regexJSONEvent  = regexp.MustCompile(`(?m)^\s*\{.*"Action"`)
+	regexTextReport = regexp.MustCompile(`(?m)^(\s*=== RUN|\s*--- (FAIL|PASS|SKIP):|ok  \t|FAIL\t|\?   \t|PASS$|FAIL$)`)
+)
+
+func init() {
+	// The formats are detected in the order of registration, plain text comes last as it's the loosest
+	RegisterParser("junit", sniffJUnit, NewTestResultParser)
+	RegisterParser("json", sniffJSON, NewTestResultJSONParser)
+	RegisterParser("text", sniffText, NewTestResultTextParser)
+}
+
+// RegisterParser registers a parser of a test result format under a name, the name can be set in
+// TEST_RESULT_FORMAT to choose the parser explicitly.
+func RegisterParser(name string, sniff FormatSniffer, factory ParserFactory) {
+	parserRegistry = append(parserRegistry, parserRegistration{
+		name:    name,
+		sniff:   sniff,
+		factory: factory,
+	})
+}
+
+// TestResultFormatDetectService parses every test result with the parser of TEST_RESULT_FORMAT,
+// or with the parser which recognizes the first bytes of the file.
+type TestResultFormatDetectService struct {
+	config  *config.Config
+	parsers map[string]TestResultParser
+}
+
+func NewTestResultFormatDetector(cfg *config.Config) TestResultParser {
+	return &TestResultFormatDetectService{
+		config:  cfg,
+		parsers: make(map[string]TestResultParser),
+	}
+}
+
+func (self *TestResultFormatDetectService) Parse(testResult string) (*TestReport, error) {
+	if testResult == "" {
+		return nil, errors.New("TestResult must not be empty")
+	}
+
+	registration, err := self.findParser(testResult)
+	if err != nil {
+		return nil, err
+	}
+
+	parser, ok := self.parsers[registration.name]
+	if !ok {
+		parser = registration.factory(self.config)
+		if self.parsers != nil {
+			self.parsers[registration.name] = parser
+		}
+	}
+	log.Printf("Parsing the test result %s as %s\n", testResult, registration.name)
+
+	return parser.Parse(testResult)
+}
+
+func (self *TestResultFormatDetectService) findParser(testResult string) (*parserRegistration, error) {
+	if self.config != nil && self.config.TestResultFormat != "" {
+		return findRegistration(self.config.TestResultFormat)
+	}
+
+	file, err := os.Open(testResult)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	for i, registration := range parserRegistry {
+		if registration.sniff(head) {
+			return &parserRegistry[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("Unable to detect the format of the test result %s. set 'TEST_RESULT_FORMAT' to one of '%s'",
+		testResult, strings.Join(parserNames(), "', '"))
+}
+
+// VerifyTestResultFormat verifies TEST_RESULT_FORMAT is empty or the name of a registered parser, so a
+// typo fails before any check run is created.
+func VerifyTestResultFormat(cfg *config.Config) error {
+	if cfg.TestResultFormat == "" {
+		return nil
+	}
+
+	_, err := findRegistration(cfg.TestResultFormat)
+	return err
+}
+
+func findRegistration(name string) (*parserRegistration, error) {
+	for i, registration := range parserRegistry {
+		if registration.name == name {
+			return &parserRegistry[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("Invalid environment variable 'TEST_RESULT_FORMAT'. should be one of '%s' instead of '%s'",
+		strings.Join(parserNames(), "', '"), name)
+}
+
+func parserNames() (names []string) {
+	for _, registration := range parserRegistry {
+		names = append(names, registration.name)
+	}
+
+	return
+}
+
+func trimHead(head []byte) []byte {
+	return bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")))
+}
+
+func sniffJUnit(head []byte) bool {
+	head = trimHead(head)
+	return bytes.HasPrefix(head, []byte("<?xml")) ||
+		bytes.HasPrefix(head, []byte("<testsuites")) ||
+		bytes.HasPrefix(head, []byte("<testsuite"))
+}
+
+func sniffJSON(head []byte) bool {
+	return regexJSONEvent.Match(trimHead(head))
+}
+
+func sniffText(head []byte) bool {
+	return regexTextReport.Match(head)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"elb2c/gh-action/config"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_passing_a_junit_report_without_a_format_detects_the_junit_format(test *testing.T) {
+	svc := NewTestResultFormatDetector(&config.Config{})
+
+	result, err := svc.Parse("../fixture/test_report_gotestsum_f.xml")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 2, len(result.Failures))
+	assert.Equal(test, "handler/user_handler_test.go", result.Failures[0].File)
+}
+
+func Test_passing_a_go_test_json_report_without_a_format_detects_the_json_format(test *testing.T) {
+	svc := NewTestResultFormatDetector(&config.Config{})
+
+	result, err := svc.Parse("../fixture/test_report_gotest_json_f.json")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 2, len(result.Failures))
+	assert.Equal(test, "handler/user_handler_test.go", result.Failures[0].File)
+}
+
+func Test_passing_a_go_test_json_report_starting_with_build_output_detects_the_json_format(test *testing.T) {
+	svc := NewTestResultFormatDetector(&config.Config{})
+
+	result, err := svc.Parse("../fixture/test_report_gotest_json_build_text.json")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 2, len(result.Failures))
+	assert.Equal(test, "TestSum", result.Failures[0].Name)
+	assert.Equal(test, "ok/ok_test.go", result.Failures[0].File)
+	assert.Equal(test, "example.com/app/broken", result.Failures[1].Package)
+}
+
+func Test_passing_a_go_test_verbose_report_without_a_format_detects_the_text_format(test *testing.T) {
+	svc := NewTestResultFormatDetector(&config.Config{})
+
+	result, err := svc.Parse("../fixture/test_report_gotest_v_f.txt")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 2, len(result.Failures))
+	assert.Equal(test, "handler/user_handler_test.go", result.Failures[0].File)
+}
+
+func Test_passing_a_report_with_an_explicit_format_uses_the_parser_of_the_format(test *testing.T) {
+	svc := NewTestResultFormatDetector(&config.Config{
+		TestResultFormat: "text",
+	})
+
+	// A JUnit report has no line the plain text parser recognizes
+	_, err := svc.Parse("../fixture/test_report_gotestsum_f.xml")
+
+	assert.Error(test, err)
+	assert.Equal(test, "Invalid test result ../fixture/test_report_gotestsum_f.xml. no test output found", err.Error())
+}
+
+func Test_passing_a_report_with_an_unknown_format_returns_an_error(test *testing.T) {
+	svc := NewTestResultFormatDetector(&config.Config{
+		TestResultFormat: "tap",
+	})
+
+	_, err := svc.Parse("../fixture/test_report_gotestsum_f.xml")
+
+	assert.Error(test, err)
+	assert.Equal(test, "Invalid environment variable 'TEST_RESULT_FORMAT'. should be one of 'junit', 'json', 'text' instead of 'tap'", err.Error())
+}
+
+func Test_verifying_an_unknown_format_returns_an_error(test *testing.T) {
+	err := VerifyTestResultFormat(&config.Config{TestResultFormat: "tap"})
+
+	assert.EqualError(test, err, "Invalid environment variable 'TEST_RESULT_FORMAT'. should be one of 'junit', 'json', 'text' instead of 'tap'")
+}
+
+func Test_verifying_a_registered_or_empty_format_returns_no_error(test *testing.T) {
+	assert.NoError(test, VerifyTestResultFormat(&config.Config{TestResultFormat: "json"}))
+	assert.NoError(test, VerifyTestResultFormat(&config.Config{}))
+}
+
+func Test_passing_a_report_in_an_unknown_format_returns_an_error(test *testing.T) {
+	file, _ := ioutil.TempFile("", "test_report")
+	file.WriteString("1..4\nok 1 - Input file opened\n")
+	file.Close()
+	defer os.Remove(file.Name())
+
+	svc := NewTestResultFormatDetector(&config.Config{})
+
+	_, err := svc.Parse(file.Name())
+
+	assert.Error(test, err)
+	assert.Contains(test, err.Error(), "Unable to detect the format of the test result")
+}
@@ -1,7 +1,9 @@
 package service
 
 import (
+	"elb2c/gh-action/config"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -12,17 +14,18 @@ func Test_passing_a_gojunit_format_report_including_test_failures_returns_test_f
 	result, err := svc.Parse("../fixture/test_report_gojunit_f.xml")
 
 	assert.NoError(test, err)
-	assert.Equal(test, 2, len(result))
-
-	assert.Equal(test, "TestList", result[0].Name)
-	assert.Equal(test, "handler/user_handler_test.go", result[0].File)
-	assert.Equal(test, 53, result[0].Line)
-	assert.Contains(test, result[0].Reason, "Not equal:")
-
-	assert.Equal(test, "TestSave_Create", result[1].Name)
-	assert.Equal(test, "repository/user_repo_test.go", result[1].File)
-	assert.Equal(test, 81, result[1].Line)
-	assert.Contains(test, result[1].Reason, "Not equal:")
+	assert.Equal(test, 2, len(result.Failures))
+
+	assert.Equal(test, "TestList", result.Failures[0].Name)
+	assert.Equal(test, "handler/user_handler_test.go", result.Failures[0].File)
+	assert.Equal(test, 53, result.Failures[0].Line)
+	assert.Contains(test, result.Failures[0].Reason, "Not equal:")
+	assert.Equal(test, "assertion", result.Failures[0].Category)
+
+	assert.Equal(test, "TestSave_Create", result.Failures[1].Name)
+	assert.Equal(test, "repository/user_repo_test.go", result.Failures[1].File)
+	assert.Equal(test, 81, result.Failures[1].Line)
+	assert.Contains(test, result.Failures[1].Reason, "Not equal:")
 }
 
 func Test_passing_a_gojunit_format_report_without_test_failures_returns_a_zero_test_failure_array(test *testing.T) {
@@ -31,7 +34,7 @@ func Test_passing_a_gojunit_format_report_without_test_failures_returns_a_zero_t
 	result, err := svc.Parse("../fixture/test_report_gojunit_s.xml")
 
 	assert.NoError(test, err)
-	assert.Equal(test, 0, len(result))
+	assert.Equal(test, 0, len(result.Failures))
 }
 
 func Test_passing_a_gotestsum_junit_format_report_including_test_failures_returns_test_failure_details(test *testing.T) {
@@ -40,17 +43,17 @@ func Test_passing_a_gotestsum_junit_format_report_including_test_failures_return
 	result, err := svc.Parse("../fixture/test_report_gotestsum_f.xml")
 
 	assert.NoError(test, err)
-	assert.Equal(test, 2, len(result))
+	assert.Equal(test, 2, len(result.Failures))
 
-	assert.Equal(test, "TestList", result[0].Name)
-	assert.Equal(test, "handler/user_handler_test.go", result[0].File)
-	assert.Equal(test, 53, result[0].Line)
-	assert.Contains(test, result[0].Reason, "Not equal:")
+	assert.Equal(test, "TestList", result.Failures[0].Name)
+	assert.Equal(test, "handler/user_handler_test.go", result.Failures[0].File)
+	assert.Equal(test, 53, result.Failures[0].Line)
+	assert.Contains(test, result.Failures[0].Reason, "Not equal:")
 
-	assert.Equal(test, "TestSave_Create", result[1].Name)
-	assert.Equal(test, "repository/user_repo_test.go", result[1].File)
-	assert.Equal(test, 81, result[1].Line)
-	assert.Contains(test, result[1].Reason, "Not equal:")
+	assert.Equal(test, "TestSave_Create", result.Failures[1].Name)
+	assert.Equal(test, "repository/user_repo_test.go", result.Failures[1].File)
+	assert.Equal(test, 81, result.Failures[1].Line)
+	assert.Contains(test, result.Failures[1].Reason, "Not equal:")
 }
 
 func Test_passing_a_gotestsum_junit_format_report_without_test_failures_returns_a_zero_test_failure_array(test *testing.T) {
@@ -59,5 +62,112 @@ func Test_passing_a_gotestsum_junit_format_report_without_test_failures_returns_
 	result, err := svc.Parse("../fixture/test_report_gotestsum_s.xml")
 
 	assert.NoError(test, err)
-	assert.Equal(test, 0, len(result))
+	assert.Equal(test, 0, len(result.Failures))
+}
+
+func Test_passing_a_gojunit_format_report_including_a_panic_returns_the_test_frame_and_the_origin_of_the_panic(test *testing.T) {
+	svc := NewTestResultParser(&config.Config{
+		AnnotatePanicOrigin: true,
+		GitHub: config.GitHub{
+			Workspace:  "/github/workspace",
+			Repository: "elb2c/rest-api-sample",
+		},
+	})
+
+	result, err := svc.Parse("../fixture/test_report_gojunit_panic.xml")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 2, len(result.Failures))
+
+	assert.Equal(test, "TestFindByID_NotFound", result.Failures[0].Name)
+	assert.Equal(test, "repository/user_repo_test.go", result.Failures[0].File)
+	assert.Equal(test, 57, result.Failures[0].Line)
+	assert.Equal(test, "panic: runtime error: index out of range", result.Failures[0].Reason)
+	assert.Equal(test, "panic", result.Failures[0].Category)
+
+	assert.Equal(test, "TestFindByID_NotFound", result.Failures[1].Name)
+	assert.Equal(test, "repository/user_repo.go", result.Failures[1].File)
+	assert.Equal(test, 42, result.Failures[1].Line)
+	assert.Contains(test, result.Failures[1].Reason, "panic: runtime error: index out of range")
+	assert.Contains(test, result.Failures[1].Reason, "goroutine 8 [running]:")
+}
+
+func Test_passing_a_gojunit_format_report_including_a_panic_without_annotating_its_origin_returns_the_test_frame(test *testing.T) {
+	svc := NewTestResultParser(&config.Config{
+		AnnotatePanicOrigin: false,
+		GitHub: config.GitHub{
+			Workspace:  "/github/workspace",
+			Repository: "elb2c/rest-api-sample",
+		},
+	})
+
+	result, err := svc.Parse("../fixture/test_report_gojunit_panic.xml")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 1, len(result.Failures))
+	assert.Equal(test, "repository/user_repo_test.go", result.Failures[0].File)
+	assert.Equal(test, 57, result.Failures[0].Line)
+}
+
+func Test_passing_a_truncated_junit_report_returns_an_error_with_the_byte_offset(test *testing.T) {
+	svc := TestResultParseService{}
+
+	_, err := svc.Parse("../fixture/test_report_gojunit_truncated.xml")
+
+	assert.Error(test, err)
+	assert.Contains(test, err.Error(), "Failed to decode the test result ../fixture/test_report_gojunit_truncated.xml at byte offset 1200")
+}
+
+func Test_passing_a_junit_report_without_test_suites_returns_an_error(test *testing.T) {
+	svc := TestResultParseService{}
+
+	_, err := svc.Parse("../fixture/test_report_gojunit_empty.xml")
+
+	assert.Error(test, err)
+	assert.Equal(test, "Invalid test result ../fixture/test_report_gojunit_empty.xml. no test suite found", err.Error())
+}
+
+func Test_passing_a_non_junit_report_returns_an_error(test *testing.T) {
+	svc := TestResultParseService{}
+
+	_, err := svc.Parse("../fixture/workspace/go.mod")
+
+	assert.Error(test, err)
+	assert.Contains(test, err.Error(), "Failed to decode the test result ../fixture/workspace/go.mod")
+}
+
+func Test_passing_test_failure_report_returns_the_statistics_of_the_packages(test *testing.T) {
+	svc := TestResultParseService{}
+
+	result, err := svc.Parse("../fixture/test_report_gojunit_f.xml")
+
+	assert.NoError(test, err)
+	assert.Equal(test, []PackageStatistics{
+		{Name: "elb2c/rest-api-sample/handler", Passed: 17, Failed: 1, Time: 18 * time.Millisecond},
+		{Name: "elb2c/rest-api-sample/repository", Passed: 5, Failed: 1, Time: 35 * time.Millisecond},
+	}, result.Packages)
+}
+
+func Test_passing_test_report_with_skipped_tests_returns_the_skipped_tests_in_the_statistics(test *testing.T) {
+	svc := TestResultParseService{}
+
+	result, err := svc.Parse("../fixture/test_report_gojunit_skipped.xml")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 0, len(result.Failures))
+	assert.Equal(test, []PackageStatistics{
+		{Name: "elb2c/rest-api-sample/repository", Passed: 2, Skipped: 1, Time: 512 * time.Millisecond},
+	}, result.Packages)
+}
+
+func Test_passing_test_failure_report_returns_the_complete_output_of_the_failures(test *testing.T) {
+	svc := TestResultParseService{}
+
+	result, err := svc.Parse("../fixture/test_report_gojunit_f.xml")
+
+	assert.NoError(test, err)
+	assert.NotContains(test, result.Failures[0].Reason, "Diff:")
+	assert.Contains(test, result.Failures[0].Details, "Error Trace:\tuser_handler_test.go:53")
+	assert.Contains(test, result.Failures[0].Details, "Diff:")
+	assert.Contains(test, result.Failures[0].Details, "Test:       \tTestList")
 }
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"strings"
+	"time"
+)
+
+// The results of a test, the same as the actions of `go test -json`
+const (
+	resultPass = "pass"
+	resultFail = "fail"
+	resultSkip = "skip"
+)
+
+// PackageStatistics how many tests of a package passed, failed and were skipped and how long they took
+type PackageStatistics struct {
+	Name    string
+	Passed  int
+	Failed  int
+	Skipped int
+	Time    time.Duration
+}
+
+// Total returns the number of the tests of the package
+func (self *PackageStatistics) Total() int {
+	return self.Passed + self.Failed + self.Skipped
+}
+
+// statisticsCollector collects the statistics of the packages in the order they show up in a test result
+type statisticsCollector struct {
+	packages []PackageStatistics
+	indexes  map[string]int
+	tests    []recordedTest
+}
+
+// recordedTest the result of a test which is counted once all the tests are known
+type recordedTest struct {
+	key    testKey
+	result string
+}
+
+func (self *statisticsCollector) get(pkg string) *PackageStatistics {
+	if self.indexes == nil {
+		self.indexes = make(map[string]int)
+	}
+
+	index, ok := self.indexes[pkg]
+	if !ok {
+		index = len(self.packages)
+		self.indexes[pkg] = index
+		self.packages = append(self.packages, PackageStatistics{Name: pkg})
+	}
+
+	return &self.packages[index]
+}
+
+func (self *statisticsCollector) count(pkg string, result string) {
+	statistics := self.get(pkg)
+	switch result {
+	case resultPass:
+		statistics.Passed++
+	case resultFail:
+		statistics.Failed++
+	case resultSkip:
+		statistics.Skipped++
+	}
+}
+
+// record records the result of a test, see collect
+func (self *statisticsCollector) record(pkg string, name string, result string) {
+	self.get(pkg)
+	self.tests = append(self.tests, recordedTest{key: testKey{pkg: pkg, name: name}, result: result})
+}
+
+// collect counts the recorded tests and returns the statistics. A test with subtests only fails or passes
+// by them, so only the tests without subtests are counted, the same as the failures.
+func (self *statisticsCollector) collect() []PackageStatistics {
+	parents := make(map[testKey]bool)
+	for _, test := range self.tests {
+		name := test.key.name
+		for index := strings.LastIndex(name, "/"); index > 0; index = strings.LastIndex(name, "/") {
+			name = name[:index]
+			parents[testKey{pkg: test.key.pkg, name: name}] = true
+		}
+	}
+
+	for _, test := range self.tests {
+		if !parents[test.key] {
+			self.count(test.key.pkg, test.result)
+		}
+	}
+	self.tests = nil
+
+	return self.packages
+}
+
+// seconds converts the seconds of a test result to a duration
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
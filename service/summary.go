@@ -0,0 +1,165 @@
+package service
+
+import (
+	"elb2c/gh-action/config"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultServerURL the server the failing tests are linked to when GITHUB_SERVER_URL is empty
+const defaultServerURL = "https://github.com"
+
+// summaryBuilder renders the markdown summary of a check run from the parsed test reports
+type summaryBuilder struct {
+	config      *config.Config
+	failures    []TestFailure
+	packages    []PackageStatistics
+	provenances []string
+}
+
+func (self *summaryBuilder) add(report *TestReport) {
+	self.failures = append(self.failures, report.Failures...)
+
+	// Packages without test files only clutter the table
+	for _, pkg := range report.Packages {
+		if pkg.Total() > 0 {
+			self.packages = append(self.packages, pkg)
+		}
+	}
+}
+
+// render renders the headline followed by the totals, a table of the packages, a collapsible list of
+// the failing tests and where the test results came from.
+func (self *summaryBuilder) render(headline string) string {
+	sections := []string{headline}
+
+	if len(self.packages) > 0 {
+		sections = append(sections, self.renderTotals(), self.renderPackages())
+	}
+
+	if len(self.failures) > 0 {
+		sections = append(sections, self.renderFailures())
+	}
+
+	if len(self.provenances) > 0 {
+		sections = append(sections, strings.Join(self.provenances, "\n"))
+	}
+
+	return strings.Join(sections, "\n\n")
+}
+
+func (self *summaryBuilder) renderTotals() string {
+	var total PackageStatistics
+	for _, pkg := range self.packages {
+		total.Passed += pkg.Passed
+		total.Failed += pkg.Failed
+		total.Skipped += pkg.Skipped
+		total.Time += pkg.Time
+	}
+
+	return fmt.Sprintf("**%d** passed, **%d** failed, **%d** skipped of %d test(s) in %s",
+		total.Passed, total.Failed, total.Skipped, total.Total(), formatDuration(total.Time))
+}
+
+func (self *summaryBuilder) renderPackages() string {
+	lines := []string{
+		"| Package | Passed | Failed | Skipped | Time |",
+		"|:---|---:|---:|---:|---:|",
+	}
+	for _, pkg := range self.packages {
+		name := "-"
+		if pkg.Name != "" {
+			name = "`" + pkg.Name + "`"
+		}
+		lines = append(lines, fmt.Sprintf("| %s | %d | %d | %d | %s |",
+			name, pkg.Passed, pkg.Failed, pkg.Skipped, formatDuration(pkg.Time)))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// renderFailures renders one line per failing test, the locations of a test failing several assertions
+// are rendered on the same line.
+func (self *summaryBuilder) renderFailures() string {
+	var keys []testKey
+	grouped := make(map[testKey][]TestFailure)
+	for _, failure := range self.failures {
+		key := testKey{pkg: failure.Package, name: failure.Name}
+		if _, ok := grouped[key]; !ok {
+			keys = append(keys, key)
+		}
+		grouped[key] = append(grouped[key], failure)
+	}
+
+	lines := []string{
+		"<details>",
+		fmt.Sprintf("<summary>%d failing test(s)</summary>", len(keys)),
+		"",
+	}
+	for _, key := range keys {
+		failures := grouped[key]
+		locations := make([]string, 0, len(failures))
+		for i, failure := range failures {
+			prefix := ""
+			if i == 0 {
+				prefix = "at "
+			}
+			locations = append(locations, self.renderLocation(failure, prefix))
+		}
+		lines = append(lines, fmt.Sprintf("- `%s` %s (%s)",
+			key.name, strings.Join(locations, ", "), formatDuration(failures[0].Time)))
+	}
+	lines = append(lines, "", "</details>")
+
+	return strings.Join(lines, "\n")
+}
+
+// renderText renders the complete output of every failing test, the output of a test failing several
+// assertions is rendered once.
+func (self *summaryBuilder) renderText() string {
+	var sections []string
+	rendered := make(map[string]bool)
+	for _, failure := range self.failures {
+		key := failure.Name + "\x00" + failure.Details
+		if failure.Details == "" || rendered[key] {
+			continue
+		}
+		rendered[key] = true
+
+		sections = append(sections, fmt.Sprintf("### `%s`\n\n%s\n\n```\n%s\n```",
+			failure.Name, self.renderLocation(failure, ""), strings.TrimSpace(failure.Details)))
+	}
+
+	return strings.Join(sections, "\n\n")
+}
+
+// renderLocation renders the link to the line of a failure, a failure without a location tells its package
+func (self *summaryBuilder) renderLocation(failure TestFailure, prefix string) string {
+	if failure.File == "" {
+		if failure.Package == "" {
+			return "without a file and line"
+		}
+		return fmt.Sprintf("in `%s` without a file and line", failure.Package)
+	}
+
+	return fmt.Sprintf("%s[%s:%d](%s)", prefix, failure.File, failure.Line, self.linkTo(failure))
+}
+
+// linkTo links the line of a failure at the commit of the check run
+func (self *summaryBuilder) linkTo(failure TestFailure) string {
+	serverURL := defaultServerURL
+	repository, sha := "", ""
+	if self.config != nil {
+		if self.config.GitHub.ServerURL != "" {
+			serverURL = strings.TrimSuffix(self.config.GitHub.ServerURL, "/")
+		}
+		repository, sha = self.config.GitHub.Repository, self.config.GitHub.SHA
+	}
+
+	return fmt.Sprintf("%s/%s/blob/%s/%s#L%d", serverURL, repository, sha, failure.File, failure.Line)
+}
+
+func formatDuration(duration time.Duration) string {
+	return fmt.Sprintf("%.2fs", duration.Seconds())
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"elb2c/gh-action/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_rendering_a_summary_leaves_out_the_packages_without_tests(test *testing.T) {
+	summary := summaryBuilder{}
+	summary.add(&TestReport{
+		Packages: []PackageStatistics{
+			{Name: "example.com/model"},
+			{Name: "example.com/handler", Passed: 2},
+		},
+	})
+
+	result := summary.render("0 test failure(s) found")
+
+	assert.Equal(test, "0 test failure(s) found\n\n"+
+		"**2** passed, **0** failed, **0** skipped of 2 test(s) in 0.00s\n\n"+
+		"| Package | Passed | Failed | Skipped | Time |\n"+
+		"|:---|---:|---:|---:|---:|\n"+
+		"| `example.com/handler` | 2 | 0 | 0 | 0.00s |", result)
+}
+
+func Test_rendering_a_summary_without_GitHub_server_URL_links_the_failing_tests_to_github_com(test *testing.T) {
+	summary := summaryBuilder{
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				SHA:        "sha",
+			},
+		},
+	}
+	summary.add(&TestReport{
+		Failures: []TestFailure{{Name: "TestList", File: "handler/user_handler_test.go", Line: 53}},
+	})
+
+	result := summary.render("1 test failure(s) found")
+
+	assert.Contains(test, result, "[handler/user_handler_test.go:53](https://github.com/octocat/Hello-World/blob/sha/handler/user_handler_test.go#L53)")
+}
+
+func Test_rendering_a_summary_of_a_test_failing_several_assertions_counts_the_test_once(test *testing.T) {
+	summary := summaryBuilder{
+		config: &config.Config{
+			GitHub: config.GitHub{
+				Repository: "octocat/Hello-World",
+				SHA:        "sha",
+			},
+		},
+	}
+	summary.add(&TestReport{
+		Failures: []TestFailure{
+			{Package: "example.com/handler", Name: "TestList", File: "handler/user_handler_test.go", Line: 53},
+			{Package: "example.com/handler", Name: "TestList", File: "handler/user_handler_test.go", Line: 60},
+			{Package: "example.com/model", Name: "TestList", File: "model/user_test.go", Line: 8},
+		},
+	})
+
+	result := summary.render("3 test failure(s) found")
+
+	assert.Contains(test, result, "<summary>2 failing test(s)</summary>\n\n"+
+		"- `TestList` at [handler/user_handler_test.go:53](https://github.com/octocat/Hello-World/blob/sha/handler/user_handler_test.go#L53), "+
+		"[handler/user_handler_test.go:60](https://github.com/octocat/Hello-World/blob/sha/handler/user_handler_test.go#L60) (0.00s)\n"+
+		"- `TestList` at [model/user_test.go:8](https://github.com/octocat/Hello-World/blob/sha/model/user_test.go#L8) (0.00s)\n")
+}
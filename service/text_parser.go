@@ -0,0 +1,154 @@
+package service
+
+import (
+	"bufio"
+	"elb2c/gh-action/config"
+	"errors"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	regexTestStarted  = regexp.MustCompile(`^\s*=== (?:RUN|CONT|PAUSE)\s+(\S+)`)
+	regexTestFinished = regexp.MustCompile(`^\s*--- (PASS|FAIL|SKIP): (\S+) \((?:([\d.]+)s\))?`)
+	regexPackageEnded = regexp.MustCompile(`^(?:FAIL|ok)\s+(\S+)\s+(?:([\d.]+)s)?`)
+)
+
+type TestResultTextParseService struct {
+	extractor failureExtractor
+}
+
+func NewTestResultTextParser(cfg *config.Config) TestResultParser {
+	return &TestResultTextParseService{
+		extractor: newFailureExtractor(cfg),
+	}
+}
+
+func (self *TestResultTextParseService) Parse(testResult string) (*TestReport, error) {
+	if testResult == "" {
+		return nil, errors.New("TestResult must not be empty")
+	}
+
+	textFile, err := os.Open(testResult)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Successful open the test result file: %s\n", testResult)
+	defer textFile.Close()
+
+	report := &TestReport{}
+	// The package of a test is only known once its trailing `FAIL <import path>` line shows up
+	outputs := make(map[string]*strings.Builder)
+	var packageOutput strings.Builder
+	var startedTests []string
+	var failedTests []testKey
+	finished := make(map[string]bool)
+	elapsed := make(map[string]float64)
+	var results []recordedTest
+	var statistics statisticsCollector
+	currentTest := ""
+	recognized := false
+	flush := func(pkg string, pkgTime float64, failed bool) {
+		if len(results) > 0 || pkg != "" {
+			for _, result := range results {
+				statistics.record(pkg, result.key.name, result.result)
+			}
+			statistics.get(pkg).Time = seconds(pkgTime)
+		}
+
+		if failed && len(failedTests) == 0 {
+			var details strings.Builder
+			for _, name := range startedTests {
+				if !finished[name] {
+					details.WriteString(outputs[name].String())
+				}
+			}
+			details.WriteString(packageOutput.String())
+
+			statistics.record(pkg, "", resultFail)
+			report.Failures = append(report.Failures, self.extractor.buildPackageFailures(pkg, details.String())...)
+		}
+
+		for _, key := range self.extractor.filterFailedTests(failedTests) {
+			var details string
+			if output := outputs[key.name]; output != nil {
+				details = output.String()
+			}
+
+			testFailures := self.extractor.buildFailures(details)
+			for _, failure := range testFailures {
+				failure.Name = key.name
+				failure.Package = pkg
+				failure.File = self.extractor.resolvePath(pkg, failure.File)
+				failure.Details = details
+				failure.Time = seconds(elapsed[key.name])
+				report.Failures = append(report.Failures, failure)
+			}
+		}
+
+		outputs = make(map[string]*strings.Builder)
+		packageOutput.Reset()
+		startedTests = nil
+		failedTests = nil
+		finished = make(map[string]bool)
+		elapsed = make(map[string]float64)
+		results = nil
+		currentTest = ""
+	}
+
+	scanner := bufio.NewScanner(textFile)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if match := regexPackageEnded.FindStringSubmatch(line); match != nil {
+			recognized = true
+			pkgTime, _ := strconv.ParseFloat(match[2], 64)
+			packageOutput.WriteString(line + "\n")
+			flush(match[1], pkgTime, strings.HasPrefix(line, "FAIL"))
+			continue
+		}
+
+		if match := regexTestStarted.FindStringSubmatch(line); match != nil {
+			recognized = true
+			currentTest = match[1]
+		} else if match := regexTestFinished.FindStringSubmatch(line); match != nil {
+			recognized = true
+			currentTest = match[2]
+			results = append(results, recordedTest{key: testKey{name: currentTest}, result: strings.ToLower(match[1])})
+			finished[currentTest] = true
+			if match[1] == "FAIL" {
+				failedTests = append(failedTests, testKey{name: currentTest})
+				elapsed[currentTest], _ = strconv.ParseFloat(match[3], 64)
+			}
+		} else if line == "PASS" || line == "FAIL" {
+			currentTest = ""
+		}
+
+		if currentTest == "" {
+			packageOutput.WriteString(line + "\n")
+			continue
+		}
+		if outputs[currentTest] == nil {
+			outputs[currentTest] = &strings.Builder{}
+			startedTests = append(startedTests, currentTest)
+		}
+		outputs[currentTest].WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := verifyNotEmpty(testResult, recognized, "test output"); err != nil {
+		return nil, err
+	}
+
+	// A log without the trailing package line, e.g. `go test -v` of a single package
+	flush("", 0, false)
+	report.Packages = statistics.collect()
+
+	return report, nil
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"elb2c/gh-action/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_passing_a_go_test_verbose_report_including_test_failures_returns_test_failure_details(test *testing.T) {
+	svc := TestResultTextParseService{}
+
+	result, err := svc.Parse("../fixture/test_report_gotest_v_f.txt")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 2, len(result.Failures))
+
+	assert.Equal(test, "TestList", result.Failures[0].Name)
+	assert.Equal(test, "handler/user_handler_test.go", result.Failures[0].File)
+	assert.Equal(test, 53, result.Failures[0].Line)
+	assert.Contains(test, result.Failures[0].Reason, "Not equal:")
+
+	assert.Equal(test, "TestSave_Create", result.Failures[1].Name)
+	assert.Equal(test, "repository/user_repo_test.go", result.Failures[1].File)
+	assert.Equal(test, 81, result.Failures[1].Line)
+	assert.Contains(test, result.Failures[1].Reason, "Not equal:")
+}
+
+func Test_passing_a_go_test_verbose_report_without_test_failures_returns_a_zero_test_failure_array(test *testing.T) {
+	svc := TestResultTextParseService{}
+
+	result, err := svc.Parse("../fixture/test_report_gotest_v_s.txt")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 0, len(result.Failures))
+}
+
+func Test_passing_an_empty_go_test_verbose_report_path_returns_an_error(test *testing.T) {
+	svc := TestResultTextParseService{}
+
+	_, err := svc.Parse("")
+
+	assert.Error(test, err)
+	assert.Equal(test, "TestResult must not be empty", err.Error())
+}
+
+func Test_passing_a_go_test_verbose_report_with_absolute_error_traces_returns_test_failures_in_the_repository(test *testing.T) {
+	svc := NewTestResultTextParser(&config.Config{
+		GitHub: config.GitHub{
+			Workspace:  "/github/workspace",
+			Repository: "elb2c/rest-api-sample",
+		},
+	})
+
+	result, err := svc.Parse("../fixture/test_report_gotest_v_abs_f.txt")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 2, len(result.Failures))
+
+	assert.Equal(test, "TestList", result.Failures[0].Name)
+	assert.Equal(test, "handler/user_handler_test.go", result.Failures[0].File)
+	assert.Equal(test, 53, result.Failures[0].Line)
+	assert.Contains(test, result.Failures[0].Reason, "Not equal:")
+
+	assert.Equal(test, "TestSave_Create", result.Failures[1].Name)
+	assert.Equal(test, "repository/user_repo_test.go", result.Failures[1].File)
+	assert.Equal(test, 81, result.Failures[1].Line)
+	assert.Contains(test, result.Failures[1].Reason, "Not equal:")
+}
+
+func Test_passing_gotest_v_failure_report_returns_the_statistics_of_the_packages(test *testing.T) {
+	svc := TestResultTextParseService{}
+
+	result, err := svc.Parse("../fixture/test_report_gotest_v_f.txt")
+
+	assert.NoError(test, err)
+	assert.Equal(test, []PackageStatistics{
+		{Name: "elb2c/rest-api-sample/handler", Passed: 17, Failed: 1, Time: 18 * time.Millisecond},
+		{Name: "elb2c/rest-api-sample/repository", Passed: 5, Failed: 1, Time: 18 * time.Millisecond},
+	}, result.Packages)
+}
+
+func Test_passing_a_go_test_v_report_including_packages_failing_without_a_failing_test_returns_package_failures(test *testing.T) {
+	svc := NewTestResultTextParser(&config.Config{
+		GitHub: config.GitHub{
+			Workspace: "/github/workspace",
+		},
+	})
+
+	result, err := svc.Parse("../fixture/test_report_gotest_v_package_f.txt")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 2, len(result.Failures))
+
+	assert.Equal(test, "example.com/app/cart", result.Failures[0].Name)
+	assert.Equal(test, "cart/cart_test.go", result.Failures[0].File)
+	assert.Equal(test, 5, result.Failures[0].Line)
+	assert.Equal(test, "undefined: NewCart", result.Failures[0].Reason)
+
+	assert.Equal(test, "TestSlow", result.Failures[1].Name)
+	assert.Equal(test, "example.com/app/slow", result.Failures[1].Package)
+	assert.Equal(test, "slow/slow_test.go", result.Failures[1].File)
+	assert.Equal(test, 9, result.Failures[1].Line)
+	assert.Equal(test, "panic: test timed out after 1s", result.Failures[1].Reason)
+	assert.Equal(test, "timeout", result.Failures[1].Category)
+
+	assert.Equal(test, []PackageStatistics{
+		{Name: "example.com/app/cart", Failed: 1},
+		{Name: "example.com/app/slow", Passed: 1, Failed: 1, Time: seconds(1.007)},
+	}, result.Packages)
+}
+
+func Test_passing_a_go_test_v_report_including_a_failing_subtest_counts_the_subtests_only(test *testing.T) {
+	svc := TestResultTextParseService{}
+
+	result, err := svc.Parse("../fixture/test_report_gotest_v_subtest_f.txt")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 1, len(result.Failures))
+	assert.Equal(test, "TestSum/negative", result.Failures[0].Name)
+	assert.Equal(test, []PackageStatistics{
+		{Name: "example.com/app/cart", Passed: 2, Failed: 1, Time: seconds(0.003)},
+	}, result.Packages)
+}
+
+func Test_passing_a_go_test_verbose_report_of_a_timed_out_subtest_returns_the_subtest_failure(test *testing.T) {
+	svc := NewTestResultTextParser(&config.Config{
+		GitHub: config.GitHub{
+			Workspace: "/github/workspace",
+		},
+	})
+
+	result, err := svc.Parse("../fixture/test_report_gotest_v_timeout.txt")
+
+	assert.NoError(test, err)
+	assert.Equal(test, 1, len(result.Failures))
+	assert.Equal(test, "TestSlow/inner", result.Failures[0].Name)
+	assert.Equal(test, "example.com/app/slow", result.Failures[0].Package)
+	assert.Equal(test, "slow/slow_test.go", result.Failures[0].File)
+	assert.Equal(test, 12, result.Failures[0].Line)
+	assert.Equal(test, "panic: test timed out after 1s", result.Failures[0].Reason)
+	assert.Equal(test, "timeout", result.Failures[0].Category)
+}
@@ -0,0 +1,40 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+)
+
+// property a property of a workflow command, the properties keep their order in the command
+type property struct {
+	name  string
+	value string
+}
+
+// formatCommand formats a workflow command like `::error file=a_test.go,line=1::message`, the empty
+// properties are left out.
+func formatCommand(command string, properties []property, message string) string {
+	var pairs []string
+	for _, p := range properties {
+		if p.value == "" {
+			continue
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%s", p.name, escapeProperty(p.value)))
+	}
+
+	if len(pairs) == 0 {
+		return fmt.Sprintf("::%s::%s", command, escapeData(message))
+	}
+
+	return fmt.Sprintf("::%s %s::%s", command, strings.Join(pairs, ","), escapeData(message))
+}
+
+// escapeData escapes the message of a workflow command, a new line would end the command
+func escapeData(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(value)
+}
+
+// escapeProperty escapes the value of a property, `:` and `,` would end the property
+func escapeProperty(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(value)
+}
@@ -0,0 +1,23 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_formatting_a_command_with_properties_returns_the_escaped_command(test *testing.T) {
+	result := formatCommand("error", []property{
+		{name: "file", value: "handler/user_handler_test.go"},
+		{name: "line", value: "53"},
+		{name: "title", value: "TestList/a:b,c"},
+	}, "Not equal: \n100%")
+
+	assert.Equal(test, "::error file=handler/user_handler_test.go,line=53,title=TestList/a%3Ab%2Cc::Not equal: %0A100%25", result)
+}
+
+func Test_formatting_a_command_without_properties_returns_the_command_with_the_message(test *testing.T) {
+	result := formatCommand("notice", []property{{name: "title", value: ""}}, "0 test failure(s) found")
+
+	assert.Equal(test, "::notice::0 test failure(s) found", result)
+}
@@ -0,0 +1,182 @@
+package workflow
+
+import (
+	"context"
+	"elb2c/gh-action/api/checkrun"
+	"elb2c/gh-action/config"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// workflowRunID the ID of the check run the reporter pretends to create, no check run exists
+	workflowRunID = -1
+	// annotationLimit how many annotations of a level GitHub shows per step, the others are dropped
+	annotationLimit = 10
+)
+
+// commandLevels the workflow commands of the annotation levels of the Checks API
+var commandLevels = map[string]string{
+	"failure": "error",
+	"warning": "warning",
+	"notice":  "notice",
+}
+
+// CommandReporter reports the test failures by the workflow commands printed to the standard output
+// instead of a check run. It needs neither the network nor a token with checks:write, so it works on
+// the pull requests from forks too.
+type CommandReporter struct {
+	out    io.Writer
+	config *config.Config
+	failed bool
+}
+
+// NewReporter creates a reporter which is both the creator and the updater of the annotator
+func NewReporter(out io.Writer, cfg *config.Config) *CommandReporter {
+	return &CommandReporter{
+		out:    out,
+		config: cfg,
+	}
+}
+
+// Create returns a placeholder of the check run, the workflow commands need no check run
+func (self *CommandReporter) Create(ctx context.Context) (*checkrun.CheckRun, error) {
+	if self.config == nil {
+		return nil, errors.New("Config must not be nil")
+	}
+
+	return &checkrun.CheckRun{
+		ID:     workflowRunID,
+		Name:   checkrun.NameOf(self.config),
+		Status: "in_progress",
+	}, nil
+}
+
+// Update prints an annotation command per annotation and appends the summary to the job summary when
+// the runner provides one.
+func (self *CommandReporter) Update(ctx context.Context, checkID int, result checkrun.Result) error {
+	if self.out == nil {
+		return errors.New("Output must not be nil")
+	}
+
+	if self.config == nil {
+		return errors.New("Config must not be nil")
+	}
+
+	if result.Annotations == nil {
+		return errors.New("Annotation array must not be nil")
+	}
+
+	var lines []string
+	counts := make(map[string]int)
+	for _, annotation := range result.Annotations {
+		lines = append(lines, formatAnnotation(annotation))
+		counts[commandOf(annotation)]++
+	}
+
+	conclusion := determineConclusion(result)
+	headline := strings.SplitN(result.Summary, "\n", 2)[0]
+	self.failed = conclusion == "failure" || conclusion == "action_required"
+
+	// A failure without annotations, e.g. a missing report, would be silent otherwise
+	if conclusion != "success" && conclusion != "neutral" && !hasLevel(result.Annotations, "failure") {
+		lines = append(lines, formatCommand("error", []property{{name: "title", value: checkrun.NameOf(self.config)}}, headline))
+	}
+	lines = append(lines, fmt.Sprintf("The tests concluded as %s: %s", conclusion, headline))
+	for _, command := range []string{"error", "warning", "notice"} {
+		if counts[command] > annotationLimit {
+			lines = append(lines, fmt.Sprintf("%d %s annotation(s) over the limit of %d per step aren't shown, see the job summary for all the test failures",
+				counts[command]-annotationLimit, command, annotationLimit))
+		}
+	}
+
+	if _, err := fmt.Fprintln(self.out, strings.Join(lines, "\n")); err != nil {
+		return fmt.Errorf("Failed to print the workflow commands because: %s", err)
+	}
+
+	return self.writeStepSummary(result)
+}
+
+// writeStepSummary appends the summary and the output of the failing tests to the job summary
+func (self *CommandReporter) writeStepSummary(result checkrun.Result) error {
+	if self.config.GitHub.StepSummary == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(self.config.GitHub.StepSummary, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("Failed to open the job summary because: %s", err)
+	}
+	defer file.Close()
+
+	sections := []string{"## " + checkrun.TitleOf(self.config), result.Summary}
+	if result.Text != "" {
+		sections = append(sections, result.Text)
+	}
+	if _, err := fmt.Fprintln(file, strings.Join(sections, "\n\n")); err != nil {
+		return fmt.Errorf("Failed to write the job summary because: %s", err)
+	}
+
+	return nil
+}
+
+// Failed whether the last update concluded the tests as failed, the step fails by the exit status then
+// since no check run fails the commit.
+func (self *CommandReporter) Failed() bool {
+	return self.failed
+}
+
+func commandOf(annotation checkrun.Annotation) string {
+	command, ok := commandLevels[annotation.Level]
+	if !ok {
+		return "error"
+	}
+
+	return command
+}
+
+func formatAnnotation(annotation checkrun.Annotation) string {
+	return formatCommand(commandOf(annotation), []property{
+		{name: "file", value: annotation.Path},
+		{name: "line", value: formatNumber(annotation.StartLine)},
+		{name: "endLine", value: formatNumber(annotation.EndLine)},
+		{name: "col", value: formatNumber(annotation.StartColumn)},
+		{name: "endColumn", value: formatNumber(annotation.EndColumn)},
+		{name: "title", value: annotation.Title},
+	}, annotation.Message)
+}
+
+func formatNumber(number int) string {
+	if number == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(number)
+}
+
+// determineConclusion the conclusion is determined by the annotations when it's empty, the same as a check run
+func determineConclusion(result checkrun.Result) string {
+	if result.Conclusion != "" {
+		return result.Conclusion
+	}
+
+	if len(result.Annotations) > 0 {
+		return "failure"
+	}
+
+	return "success"
+}
+
+func hasLevel(annotations []checkrun.Annotation, level string) bool {
+	for _, annotation := range annotations {
+		if annotation.Level == level {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,132 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"elb2c/gh-action/api/checkrun"
+	"elb2c/gh-action/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_creating_returns_a_placeholder_of_the_check_run(test *testing.T) {
+	reporter := NewReporter(&bytes.Buffer{}, &config.Config{})
+
+	result, err := reporter.Create(context.Background())
+
+	assert.NoError(test, err)
+	assert.Equal(test, -1, result.ID)
+	assert.Equal(test, "Test failure annotator", result.Name)
+}
+
+func Test_passing_nil_config_when_creating_returns_an_error(test *testing.T) {
+	reporter := NewReporter(&bytes.Buffer{}, nil)
+
+	_, err := reporter.Create(context.Background())
+
+	assert.Error(test, err)
+}
+
+func Test_passing_annotations_prints_a_workflow_command_per_annotation(test *testing.T) {
+	out := &bytes.Buffer{}
+	reporter := NewReporter(out, &config.Config{})
+
+	err := reporter.Update(context.Background(), -1, checkrun.Result{
+		Conclusion: "failure",
+		Summary:    "2 test failure(s) found\n\n- `test_report.xml`: 2 test failure(s)",
+		Annotations: []checkrun.Annotation{
+			{Title: "TestList", Path: "handler/user_handler_test.go", StartLine: 53, EndLine: 53, Level: "failure", Message: "Not equal: "},
+			{Title: "TestCheckout", Path: "e2e/checkout_test.go", StartLine: 31, EndLine: 31, Level: "warning", Message: "timed out"},
+		},
+	})
+
+	assert.NoError(test, err)
+	assert.Equal(test, "::error file=handler/user_handler_test.go,line=53,endLine=53,title=TestList::Not equal: \n"+
+		"::warning file=e2e/checkout_test.go,line=31,endLine=31,title=TestCheckout::timed out\n"+
+		"The tests concluded as failure: 2 test failure(s) found\n", out.String())
+}
+
+func Test_passing_a_failure_without_annotations_prints_an_error_command(test *testing.T) {
+	out := &bytes.Buffer{}
+	reporter := NewReporter(out, &config.Config{})
+
+	err := reporter.Update(context.Background(), -1, checkrun.Result{
+		Conclusion:  "failure",
+		Summary:     "No test result found by TEST_RESULT '/'",
+		Annotations: make([]checkrun.Annotation, 0),
+	})
+
+	assert.NoError(test, err)
+	assert.Equal(test, "::error title=Test failure annotator::No test result found by TEST_RESULT '/'\n"+
+		"The tests concluded as failure: No test result found by TEST_RESULT '/'\n", out.String())
+}
+
+func Test_passing_a_failure_fails_the_reporter(test *testing.T) {
+	for conclusion, failed := range map[string]bool{"failure": true, "action_required": true, "neutral": false, "success": false} {
+		reporter := NewReporter(&bytes.Buffer{}, &config.Config{})
+
+		err := reporter.Update(context.Background(), -1, checkrun.Result{
+			Conclusion:  conclusion,
+			Summary:     "1 test failure(s) found",
+			Annotations: make([]checkrun.Annotation, 0),
+		})
+
+		assert.NoError(test, err)
+		assert.Equal(test, failed, reporter.Failed(), conclusion)
+	}
+}
+
+func Test_passing_annotations_over_the_limit_of_a_step_prints_how_many_are_not_shown(test *testing.T) {
+	out := &bytes.Buffer{}
+	reporter := NewReporter(out, &config.Config{})
+	var annotations []checkrun.Annotation
+	for i := 1; i <= 12; i++ {
+		annotations = append(annotations, checkrun.Annotation{Title: "TestList", Path: "list_test.go", StartLine: i, EndLine: i, Level: "failure", Message: "failed"})
+	}
+	annotations = append(annotations, checkrun.Annotation{Title: "TestSlow", Path: "slow_test.go", StartLine: 1, EndLine: 1, Level: "warning", Message: "timed out"})
+
+	err := reporter.Update(context.Background(), -1, checkrun.Result{
+		Conclusion:  "failure",
+		Summary:     "13 test failure(s) found",
+		Annotations: annotations,
+	})
+
+	assert.NoError(test, err)
+	assert.Contains(test, out.String(), "The tests concluded as failure: 13 test failure(s) found\n"+
+		"2 error annotation(s) over the limit of 10 per step aren't shown, see the job summary for all the test failures\n")
+	assert.NotContains(test, out.String(), "warning annotation(s) over the limit")
+}
+
+func Test_passing_nil_annotations_returns_an_error(test *testing.T) {
+	reporter := NewReporter(&bytes.Buffer{}, &config.Config{})
+
+	err := reporter.Update(context.Background(), -1, checkrun.Result{Conclusion: "success"})
+
+	assert.Error(test, err)
+}
+
+func Test_setting_a_step_summary_appends_the_summary_and_the_text_to_it(test *testing.T) {
+	dir, _ := ioutil.TempDir("", "workflow")
+	defer os.RemoveAll(dir)
+	stepSummary := filepath.Join(dir, "step_summary.md")
+	ioutil.WriteFile(stepSummary, []byte("# Build\n"), 0644)
+
+	reporter := NewReporter(&bytes.Buffer{}, &config.Config{
+		GitHub: config.GitHub{StepSummary: stepSummary},
+	})
+
+	err := reporter.Update(context.Background(), -1, checkrun.Result{
+		Conclusion:  "failure",
+		Summary:     "1 test failure(s) found",
+		Text:        "### `TestList`",
+		Annotations: make([]checkrun.Annotation, 0),
+	})
+
+	assert.NoError(test, err)
+	content, _ := ioutil.ReadFile(stepSummary)
+	assert.Equal(test, "# Build\n## Test failure details\n\n1 test failure(s) found\n\n### `TestList`\n", string(content))
+}